@@ -0,0 +1,126 @@
+package client
+
+import (
+	"sort"
+
+	"a2a/models"
+)
+
+// ArtifactAssembler stitches artifacts streamed in chunks back together.
+// Chunks are matched by Index, so chunks of different artifacts may
+// interleave. An artifact is complete when a chunk has LastChunk set, or
+// when a chunk that neither appends nor declares LastChunk arrives, which is
+// how unchunked artifacts are sent.
+type ArtifactAssembler struct {
+	pending map[int]*pendingArtifact
+}
+
+// pendingArtifact is an artifact still waiting for its last chunk
+type pendingArtifact struct {
+	taskID   string
+	artifact models.Artifact
+}
+
+// NewArtifactAssembler creates an empty ArtifactAssembler
+func NewArtifactAssembler() *ArtifactAssembler {
+	return &ArtifactAssembler{pending: make(map[int]*pendingArtifact)}
+}
+
+// Add applies a chunk, returning the whole artifact once it is complete
+func (a *ArtifactAssembler) Add(chunk models.Artifact) (models.Artifact, bool) {
+	artifact, ok := a.add("", chunk)
+	return artifact.artifact, ok
+}
+
+func (a *ArtifactAssembler) add(taskID string, chunk models.Artifact) (pendingArtifact, bool) {
+	index := 0
+	if chunk.Index != nil {
+		index = *chunk.Index
+	}
+
+	p, exists := a.pending[index]
+	appending := chunk.Append != nil && *chunk.Append
+	if exists && appending {
+		parts := make([]models.Part, 0, len(p.artifact.Parts)+len(chunk.Parts))
+		parts = append(parts, p.artifact.Parts...)
+		p.artifact.Parts = append(parts, chunk.Parts...)
+		p.artifact.LastChunk = chunk.LastChunk
+	} else {
+		p = &pendingArtifact{taskID: taskID, artifact: chunk}
+		p.artifact.Parts = append([]models.Part(nil), chunk.Parts...)
+	}
+	// The reassembled artifact is whole, so it no longer appends to anything
+	p.artifact.Append = nil
+
+	last := chunk.LastChunk
+	if (last != nil && *last) || (last == nil && !appending) {
+		delete(a.pending, index)
+		return *p, true
+	}
+	a.pending[index] = p
+	return pendingArtifact{}, false
+}
+
+// Flush returns the artifacts whose last chunk never arrived, ordered by
+// index, and forgets them. Call it when the stream ends.
+func (a *ArtifactAssembler) Flush() []models.Artifact {
+	var artifacts []models.Artifact
+	for _, p := range a.flush() {
+		artifacts = append(artifacts, p.artifact)
+	}
+	return artifacts
+}
+
+func (a *ArtifactAssembler) flush() []pendingArtifact {
+	indexes := make([]int, 0, len(a.pending))
+	for index := range a.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	partial := make([]pendingArtifact, 0, len(indexes))
+	for _, index := range indexes {
+		partial = append(partial, *a.pending[index])
+	}
+	a.pending = make(map[int]*pendingArtifact)
+	return partial
+}
+
+// ReassembleArtifacts copies streaming events from in to out, replacing
+// artifact chunks with one *models.TaskArtifactUpdateEvent per complete
+// artifact. Other events pass through unchanged. When in is closed,
+// artifacts missing their last chunk are sent as they stand and out is
+// closed. It reads the events SendTaskStreaming produces:
+//
+//	raw := make(chan any)
+//	events := make(chan any)
+//	go client.ReassembleArtifacts(raw, events)
+//	go func() {
+//		defer close(raw)
+//		c.SendTaskStreaming(params, raw)
+//	}()
+//	for event := range events { ... }
+func ReassembleArtifacts(in <-chan any, out chan<- any) {
+	defer close(out)
+
+	assembler := NewArtifactAssembler()
+	for event := range in {
+		update, ok := event.(*models.TaskArtifactUpdateEvent)
+		if !ok {
+			out <- event
+			continue
+		}
+
+		complete, ok := assembler.add(update.ID, update.Artifact)
+		if !ok {
+			continue
+		}
+		reassembled := *update
+		reassembled.Artifact = complete.artifact
+		out <- &reassembled
+	}
+
+	for _, p := range assembler.flush() {
+		out <- &models.TaskArtifactUpdateEvent{ID: p.taskID, Artifact: p.artifact}
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCardCacheTTL is how long agent cards are cached when the agent
+// doesn't say otherwise
+const defaultCardCacheTTL = 5 * time.Minute
+
+// WithCardCacheTTL sets how long GetAgentCard reuses a fetched agent card.
+// A max-age or no-cache directive in the agent's Cache-Control header takes
+// precedence, unless ttl is zero, which disables caching. Defaults to 5
+// minutes.
+func WithCardCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cardTTL = ttl
+	}
+}
+
+// cacheControlMaxAge returns how long a Cache-Control header allows a
+// response to be reused, reporting false if the header doesn't say
+func cacheControlMaxAge(header string) (time.Duration, bool) {
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// SupportsInputMode reports whether a skill accepts content in the given
+// input mode, such as "text" or "file", so that callers can avoid sending
+// parts the agent would reject. Skills that declare no input modes accept the
+// agent's DefaultInputModes. The agent card is cached as for GetAgentCard.
+func (c *Client) SupportsInputMode(skillID, mode string) (bool, error) {
+	return c.SupportsInputModeContext(context.Background(), skillID, mode)
+}
+
+// SupportsInputModeContext is like SupportsInputMode but honors ctx for cancellation and deadlines
+func (c *Client) SupportsInputModeContext(ctx context.Context, skillID, mode string) (bool, error) {
+	card, err := c.GetAgentCardContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, skill := range card.Skills {
+		if skill.ID != skillID {
+			continue
+		}
+		modes := skill.InputModes
+		if len(modes) == 0 {
+			modes = card.DefaultInputModes
+		}
+		return slices.Contains(modes, mode), nil
+	}
+	return false, fmt.Errorf("agent has no skill %q", skillID)
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestSupportsInputMode(t *testing.T) {
+	var fetches int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(models.AgentCard{
+			Name:              "Test Agent",
+			DefaultInputModes: []string{"text", "file"},
+			Skills: []models.AgentSkill{
+				{ID: "chat", Name: "Chat", InputModes: []string{"text"}},
+				{ID: "summarize", Name: "Summarize"},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	tests := []struct {
+		skill string
+		mode  string
+		want  bool
+	}{
+		{skill: "chat", mode: "text", want: true},
+		{skill: "chat", mode: "file", want: false},
+		{skill: "summarize", mode: "file", want: true},
+	}
+	for _, tt := range tests {
+		got, err := client.SupportsInputMode(tt.skill, tt.mode)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("SupportsInputMode(%q, %q) = %v, want %v", tt.skill, tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := client.SupportsInputMode("missing", "text"); err == nil {
+		t.Error("expected an error for an unknown skill")
+	}
+	if fetches != 1 {
+		t.Errorf("expected the agent card to be fetched once, got %d fetches", fetches)
+	}
+}
+
+func TestAgentCardCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		opts         []Option
+		wantFetches  int
+	}{
+		{name: "cached within TTL", wantFetches: 1},
+		{name: "max-age", cacheControl: "public, max-age=60", wantFetches: 1},
+		{name: "no-cache", cacheControl: "no-cache", wantFetches: 2},
+		{name: "expired max-age", cacheControl: "max-age=0", wantFetches: 2},
+		{name: "caching disabled", cacheControl: "max-age=60", opts: []Option{WithCardCacheTTL(0)}, wantFetches: 2},
+		{name: "TTL elapsed", opts: []Option{WithCardCacheTTL(time.Nanosecond)}, wantFetches: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				json.NewEncoder(w).Encode(models.AgentCard{Name: "Test Agent"})
+			}))
+			defer ts.Close()
+
+			client := NewClient(ts.URL, tt.opts...)
+			for i := 0; i < 2; i++ {
+				card, err := client.GetAgentCard()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if card.Name != "Test Agent" {
+					t.Errorf("expected card name Test Agent, got %q", card.Name)
+				}
+			}
+			if fetches != tt.wantFetches {
+				t.Errorf("expected %d fetches, got %d", tt.wantFetches, fetches)
+			}
+		})
+	}
+}
+
+func TestRefreshCard(t *testing.T) {
+	var fetches int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(models.AgentCard{Name: "Test Agent", Version: fmt.Sprint(fetches)})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	if _, err := client.GetAgentCard(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RefreshCard(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refreshed card replaces the cached one
+	card, err := client.GetAgentCard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 || card.Version != "2" {
+		t.Errorf("expected the refreshed card after 2 fetches, got version %q after %d", card.Version, fetches)
+	}
+}
@@ -1,13 +1,21 @@
 package client
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/propagation"
+
 	"a2a/models"
 )
 
@@ -15,169 +23,606 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	retry      retryPolicy
+	reconnect  retryPolicy
+	headers    http.Header
+	lastID     atomic.Int64
+
+	// checkRedirect, when set, overrides the HTTP client's redirect policy
+	checkRedirect func(req *http.Request, via []*http.Request) error
+
+	// card caches the last agent card fetched until cardExpires
+	cardTTL     time.Duration
+	cardMu      sync.Mutex
+	card        *models.AgentCard
+	cardExpires time.Time
+}
+
+// agentCardPath is the well-known path where agents publish their card
+const agentCardPath = "/.well-known/agent.json"
+
+// healthPath is where agents report liveness
+const healthPath = "/healthz"
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests, e.g. to configure
+// TLS, proxies, or an instrumented transport
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithBearerToken authenticates every request with an
+// "Authorization: Bearer <token>" header
+func WithBearerToken(token string) Option {
+	return WithAuthHeader("Authorization", "Bearer "+token)
+}
+
+// WithAuthHeader sets a header on every request, for agents whose
+// authentication scheme uses a custom header such as an API key
+func WithAuthHeader(name, value string) Option {
+	return func(c *Client) {
+		c.headers.Set(name, value)
+	}
+}
+
+// WithRedirectPolicy controls which redirects the client follows, with the
+// same contract as http.Client.CheckRedirect. By default the client follows
+// redirects only within the same host, since forwarding credentials to
+// another host can leak them and auth proxies often drop them anyway; a
+// redirect that isn't followed is reported as an unexpected status code.
+func WithRedirectPolicy(policy func(req *http.Request, via []*http.Request) error) Option {
+	return func(c *Client) {
+		c.checkRedirect = policy
+	}
 }
 
-// NewClient creates a new A2A client
-func NewClient(baseURL string) *Client {
-	return &Client{
+// sameHostRedirects follows up to 10 redirects as long as they stay on the
+// original request's host
+func sameHostRedirects(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// NewClient creates a new A2A client. An invalid base URL is not reported
+// until the first request fails; use NewClientE to check it up front.
+func NewClient(baseURL string, opts ...Option) *Client {
+	if normalized, err := normalizeBaseURL(baseURL); err == nil {
+		baseURL = normalized
+	}
+	return newClient(baseURL, opts...)
+}
+
+// NewClientE is like NewClient but returns an error if baseURL is not an
+// absolute http or https URL
+func NewClientE(baseURL string, opts ...Option) (*Client, error) {
+	normalized, err := normalizeBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(normalized, opts...), nil
+}
+
+// normalizeBaseURL checks that baseURL is an absolute http or https URL and
+// returns it cleaned: scheme and host lowercased, duplicate slashes in the
+// path collapsed and any fragment dropped. A trailing slash is kept, since
+// servers may route on it.
+func normalizeBaseURL(baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q: must be an absolute http or https URL, e.g. http://localhost:8080", baseURL)
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	for strings.Contains(u.Path, "//") {
+		u.Path = strings.ReplaceAll(u.Path, "//", "/")
+	}
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+func newClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		headers: make(http.Header),
+		cardTTL: defaultCardCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Apply the redirect policy to a copy so that an HTTP client passed to
+	// WithHTTPClient isn't modified; its own policy is kept if it has one
+	if c.checkRedirect != nil || c.httpClient.CheckRedirect == nil {
+		hc := *c.httpClient
+		hc.CheckRedirect = c.checkRedirect
+		if hc.CheckRedirect == nil {
+			hc.CheckRedirect = sameHostRedirects
+		}
+		c.httpClient = &hc
+	}
+	return c
+}
+
+// GetAgentCard fetches the agent card from the agent's well-known endpoint.
+// Cards are cached for the duration set by WithCardCacheTTL or the agent's
+// Cache-Control header; call RefreshCard to force a reload.
+func (c *Client) GetAgentCard() (*models.AgentCard, error) {
+	return c.GetAgentCardContext(context.Background())
+}
+
+// GetAgentCardContext is like GetAgentCard but honors ctx for cancellation and deadlines
+func (c *Client) GetAgentCardContext(ctx context.Context) (*models.AgentCard, error) {
+	c.cardMu.Lock()
+	if c.card != nil && time.Now().Before(c.cardExpires) {
+		card := *c.card
+		c.cardMu.Unlock()
+		return &card, nil
+	}
+	c.cardMu.Unlock()
+
+	return c.RefreshCardContext(ctx)
+}
+
+// RefreshCard fetches the agent card, bypassing and then updating the cache
+func (c *Client) RefreshCard() (*models.AgentCard, error) {
+	return c.RefreshCardContext(context.Background())
+}
+
+// RefreshCardContext is like RefreshCard but honors ctx for cancellation and deadlines
+func (c *Client) RefreshCardContext(ctx context.Context) (*models.AgentCard, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.endpoint(agentCardPath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(httpReq, models.NewRequestID())
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: httpResp.StatusCode}
+	}
+
+	var card models.AgentCard
+	if err := json.NewDecoder(httpResp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("failed to decode agent card: %w", err)
+	}
+
+	ttl := c.cardTTL
+	if maxAge, ok := cacheControlMaxAge(httpResp.Header.Get("Cache-Control")); ok && ttl > 0 {
+		ttl = maxAge
+	}
+
+	// Cache a copy so that callers modifying the card don't affect it
+	cached := card
+	c.cardMu.Lock()
+	c.card = &cached
+	c.cardExpires = time.Now().Add(ttl)
+	c.cardMu.Unlock()
+	return &card, nil
+}
+
+// Ping checks that the agent is up, returning its reported health
+func (c *Client) Ping() (*models.HealthStatus, error) {
+	return c.PingContext(context.Background())
+}
+
+// PingContext is like Ping but honors ctx for cancellation and deadlines
+func (c *Client) PingContext(ctx context.Context) (*models.HealthStatus, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.endpoint(healthPath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(httpReq, models.NewRequestID())
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: httpResp.StatusCode}
+	}
+
+	var health models.HealthStatus
+	if err := json.NewDecoder(httpResp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to decode health status: %w", err)
 	}
+
+	return &health, nil
 }
 
 // SendTask sends a task message to the agent
 func (c *Client) SendTask(params models.TaskSendParams) (*models.JSONRPCResponse, error) {
+	return c.SendTaskContext(context.Background(), params)
+}
+
+// SendTaskContext is like SendTask but honors ctx for cancellation and deadlines
+func (c *Client) SendTaskContext(ctx context.Context, params models.TaskSendParams) (*models.JSONRPCResponse, error) {
 	req := models.JSONRPCRequest{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
 		},
-		Method: "message/send",
+		Method: models.MethodMessageSend,
 		Params: params,
 	}
 
 	var resp models.JSONRPCResponse
-	if err := c.doRequest(req, &resp); err != nil {
+	if err := c.doRequest(ctx, req, &resp); err != nil {
 		return nil, err
 	}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("A2A error: %s (code: %d)", resp.Error.Message, resp.Error.Code)
-	}
-
 	return &resp, nil
 }
 
 // GetTask retrieves the status of a task
 func (c *Client) GetTask(params models.TaskQueryParams) (*models.JSONRPCResponse, error) {
+	return c.GetTaskContext(context.Background(), params)
+}
+
+// GetTaskContext is like GetTask but honors ctx for cancellation and deadlines
+func (c *Client) GetTaskContext(ctx context.Context, params models.TaskQueryParams) (*models.JSONRPCResponse, error) {
 	req := models.JSONRPCRequest{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
 		},
-		Method: "tasks/get",
+		Method: models.MethodTasksGet,
 		Params: params,
 	}
 
 	var resp models.JSONRPCResponse
-	if err := c.doRequest(req, &resp); err != nil {
+	if err := c.doRequest(ctx, req, &resp); err != nil {
 		return nil, err
 	}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("A2A error: %s (code: %d)", resp.Error.Message, resp.Error.Code)
-	}
-
 	return &resp, nil
 }
 
 // CancelTask cancels a task
 func (c *Client) CancelTask(params models.TaskIDParams) (*models.JSONRPCResponse, error) {
+	return c.CancelTaskContext(context.Background(), params)
+}
+
+// CancelTaskContext is like CancelTask but honors ctx for cancellation and deadlines
+func (c *Client) CancelTaskContext(ctx context.Context, params models.TaskIDParams) (*models.JSONRPCResponse, error) {
 	req := models.JSONRPCRequest{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
 		},
-		Method: "tasks/cancel",
+		Method: models.MethodTasksCancel,
 		Params: params,
 	}
 
 	var resp models.JSONRPCResponse
-	if err := c.doRequest(req, &resp); err != nil {
+	if err := c.doRequest(ctx, req, &resp); err != nil {
 		return nil, err
 	}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("A2A error: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+	return &resp, nil
+}
+
+// DeleteTask permanently removes a task, its history and its push
+// notification config from the agent. Running tasks must be canceled first.
+func (c *Client) DeleteTask(params models.TaskIDParams) (*models.JSONRPCResponse, error) {
+	return c.DeleteTaskContext(context.Background(), params)
+}
+
+// DeleteTaskContext is like DeleteTask but honors ctx for cancellation and deadlines
+func (c *Client) DeleteTaskContext(ctx context.Context, params models.TaskIDParams) (*models.JSONRPCResponse, error) {
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksDelete,
+		Params: params,
+	}
+
+	var resp models.JSONRPCResponse
+	if err := c.doRequest(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ListTasks retrieves a page of the tasks known to the agent, most recently
+// created first. Pass the returned NextCursor as params.Cursor to fetch the
+// following page.
+func (c *Client) ListTasks(params models.ListTasksParams) (*models.JSONRPCResponse, error) {
+	return c.ListTasksContext(context.Background(), params)
+}
+
+// ListTasksContext is like ListTasks but honors ctx for cancellation and deadlines
+func (c *Client) ListTasksContext(ctx context.Context, params models.ListTasksParams) (*models.JSONRPCResponse, error) {
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksList,
+		Params: params,
+	}
+
+	var resp models.JSONRPCResponse
+	if err := c.doRequest(ctx, req, &resp); err != nil {
+		return nil, err
 	}
 
 	return &resp, nil
 }
 
-// SendTaskStreaming sends a task message and streams the response
+// SetPushNotification registers where the agent should send notifications
+// about a task, returning the config the agent stored
+func (c *Client) SetPushNotification(cfg models.TaskPushNotificationConfig) (*models.PushNotificationConfig, error) {
+	return c.SetPushNotificationContext(context.Background(), cfg)
+}
+
+// SetPushNotificationContext is like SetPushNotification but honors ctx for cancellation and deadlines
+func (c *Client) SetPushNotificationContext(ctx context.Context, cfg models.TaskPushNotificationConfig) (*models.PushNotificationConfig, error) {
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksPushNotificationSet,
+		Params: cfg,
+	}
+
+	var resp models.JSONRPCResponse
+	if err := c.doRequest(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.AsPushConfig()
+}
+
+// GetPushNotification retrieves the push notification config of a task
+func (c *Client) GetPushNotification(params models.TaskIDParams) (*models.PushNotificationConfig, error) {
+	return c.GetPushNotificationContext(context.Background(), params)
+}
+
+// GetPushNotificationContext is like GetPushNotification but honors ctx for cancellation and deadlines
+func (c *Client) GetPushNotificationContext(ctx context.Context, params models.TaskIDParams) (*models.PushNotificationConfig, error) {
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksPushNotificationGet,
+		Params: params,
+	}
+
+	var resp models.JSONRPCResponse
+	if err := c.doRequest(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.AsPushConfig()
+}
+
+// SendTaskStreaming sends a task message and streams the response. Each
+// event is sent to eventChan as a *models.TaskStatusUpdateEvent or a
+// *models.TaskArtifactUpdateEvent.
 func (c *Client) SendTaskStreaming(params models.TaskSendParams, eventChan chan<- any) error {
+	return c.SendTaskStreamingContext(context.Background(), params, eventChan)
+}
+
+// SendTaskStreamingContext is like SendTaskStreaming but stops streaming when ctx is done
+func (c *Client) SendTaskStreamingContext(ctx context.Context, params models.TaskSendParams, eventChan chan<- any) error {
 	req := models.JSONRPCRequest{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
 		},
-		Method: "message/stream",
+		Method: models.MethodMessageStream,
 		Params: params,
 	}
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+	return c.doStreamingRequest(ctx, req, sendTo(eventChan))
+}
+
+// Resubscribe reattaches to a task's event stream, e.g. after a dropped connection
+func (c *Client) Resubscribe(params models.TaskQueryParams, eventChan chan<- any) error {
+	return c.ResubscribeContext(context.Background(), params, eventChan)
+}
+
+// ResubscribeContext is like Resubscribe but stops streaming when ctx is done
+func (c *Client) ResubscribeContext(ctx context.Context, params models.TaskQueryParams, eventChan chan<- any) error {
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksResubscribe,
+		Params: params,
+	}
+
+	return c.doStreamingRequest(ctx, req, sendTo(eventChan))
+}
+
+// sendTo returns a deliver function for doStreamingRequest that sends each
+// event to eventChan
+func sendTo(eventChan chan<- any) func(context.Context, models.StreamEvent) error {
+	return func(ctx context.Context, event models.StreamEvent) error {
+		select {
+		case eventChan <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// doStreamingRequest performs a streaming request, passing each event result
+// to deliver and stopping at the first error it returns. A stream that drops
+// before its final event is resumed through tasks/resubscribe when
+// WithStreamReconnect allows it.
+func (c *Client) doStreamingRequest(ctx context.Context, req models.JSONRPCRequest, deliver func(context.Context, models.StreamEvent) error) error {
+	st := &streamState{seen: make(map[string]bool)}
+	dropped, err := c.streamOnce(ctx, req, st, deliver)
+	for attempt := 0; dropped && st.taskID != "" && attempt < c.reconnect.maxAttempts; attempt++ {
+		if err := c.reconnect.wait(ctx, attempt); err != nil {
+			return err
+		}
+		dropped, err = c.streamOnce(ctx, resubscribeRequest(st.taskID), st, deliver)
 	}
+	return err
+}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(body))
+// streamOnce makes one streaming request, resuming after st.lastEventID if
+// set. dropped reports that the connection was lost before the final event,
+// in which case err describes how, or is nil if the server simply closed it.
+func (c *Client) streamOnce(ctx context.Context, req models.JSONRPCRequest, st *streamState, deliver func(context.Context, models.StreamEvent) error) (dropped bool, err error) {
+	req.ID = c.nextRequestID()
+	httpReq, err := newJSONRequest(ctx, c.baseURL, req)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	requestID := models.NewRequestID()
+	c.setHeaders(httpReq, requestID)
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "text/event-stream")
+	if st.lastEventID != "" {
+		httpReq.Header.Set(models.LastEventIDHeader, st.lastEventID)
+	}
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return ctx.Err() == nil, fmt.Errorf("failed to send request %s: %w", requestID, err)
 	}
 	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", httpResp.StatusCode)
+		return false, fmt.Errorf("unexpected status code %d for request %s", httpResp.StatusCode, requestID)
+	}
+
+	// Errors detected before the stream starts arrive as a plain JSON-RPC response
+	if strings.HasPrefix(httpResp.Header.Get("Content-Type"), "application/json") {
+		var resp models.JSONRPCResponse
+		if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if resp.Error != nil {
+			return false, newA2AError(resp.Error, requestID)
+		}
+		return false, nil
 	}
 
-	decoder := json.NewDecoder(httpResp.Body)
+	reader := newSSEReader(httpResp.Body)
 	for {
-		var event models.SendTaskStreamingResponse
-		if err := decoder.Decode(&event); err != nil {
+		frame, err := reader.Next()
+		if err != nil {
 			if err == io.EOF {
-				break
+				return true, nil
 			}
-			return fmt.Errorf("failed to decode event: %w", err)
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return true, fmt.Errorf("failed to read event: %w", err)
+		}
+
+		// Events replayed after a reconnect were already delivered
+		if frame.ID != "" && st.seen[frame.ID] {
+			continue
+		}
+
+		var event models.SendTaskStreamingResponse
+		if err := json.Unmarshal([]byte(frame.Data), &event); err != nil {
+			return false, fmt.Errorf("failed to decode event: %w", err)
 		}
 
 		if event.Error != nil {
-			return fmt.Errorf("A2A error: %s (code: %d)", event.Error.Message, event.Error.Code)
+			// The event's code decodes into A2AError.Code, shadowing the embedded one
+			rpcErr := event.Error.JSONRPCError
+			rpcErr.Code = int(event.Error.Code)
+			return false, newA2AError(&rpcErr, requestID)
 		}
 		jsonres, err := json.Marshal(event.Result)
 		if err != nil {
-			return fmt.Errorf("failed to encode event result: %w", err)
+			return false, fmt.Errorf("failed to encode event result: %w", err)
 		}
-		select {
-		case eventChan <- json.RawMessage(jsonres):
-		case <-httpReq.Context().Done():
-			return httpReq.Context().Err()
+		streamEvent, err := models.DecodeStreamEvent(jsonres)
+		if err != nil {
+			return false, fmt.Errorf("failed to decode event: %w", err)
+		}
+		if err := deliver(ctx, streamEvent); err != nil {
+			return false, err
+		}
+		st.received(frame.ID, streamEvent)
+
+		// Servers may keep the connection open after the final event, so
+		// don't wait for them to close it
+		if isFinalStatusEvent(streamEvent) {
+			return false, nil
 		}
 	}
+}
 
-	return nil
+// isFinalStatusEvent reports whether an event is a TaskStatusUpdateEvent marked final
+func isFinalStatusEvent(event models.StreamEvent) bool {
+	status, ok := event.(*models.TaskStatusUpdateEvent)
+	return ok && status.Final != nil && *status.Final
 }
 
-// doRequest performs the HTTP request and handles the response
-func (c *Client) doRequest(req interface{}, resp *models.JSONRPCResponse) error {
-	body, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+// doRequest performs the HTTP request and handles the response, returning
+// an *A2AError if the agent responded with a JSON-RPC error. Idempotent
+// methods are retried according to the client's retry policy, with every
+// attempt sharing one X-Request-ID.
+func (c *Client) doRequest(ctx context.Context, req models.JSONRPCRequest, resp *models.JSONRPCResponse) error {
+	req.ID = c.nextRequestID()
+	requestID := models.NewRequestID()
+
+	attempts := 1
+	if idempotentMethods[req.Method] && c.retry.maxAttempts > 1 {
+		attempts = c.retry.maxAttempts
 	}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	var httpResp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		httpResp, err = c.post(ctx, req, requestID)
+		if attempt+1 >= attempts || !shouldRetry(ctx, httpResp, err) {
+			break
+		}
+		if httpResp != nil {
+			httpResp.Body.Close()
+		}
+		if err := c.retry.wait(ctx, attempt); err != nil {
+			return err
+		}
 	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request %s: %w", requestID, err)
 	}
 	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", httpResp.StatusCode)
+		return fmt.Errorf("unexpected status code %d for request %s", httpResp.StatusCode, requestID)
 	}
 
-	// First decode into a map to handle the Result field correctly
+	// Decode the envelope, keeping the result as raw JSON
 	var rawResp struct {
 		JSONRPC string               `json:"jsonrpc"`
 		ID      interface{}          `json:"id,omitempty"`
@@ -189,19 +634,93 @@ func (c *Client) doRequest(req interface{}, resp *models.JSONRPCResponse) error
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	// A null ID is only allowed on errors the server could not tie to a request
+	if (rawResp.ID != nil || rawResp.Error == nil) && fmt.Sprint(rawResp.ID) != req.ID {
+		return fmt.Errorf("response ID %v does not match request ID %v", rawResp.ID, req.ID)
+	}
+
 	// Copy the basic fields
 	resp.JSONRPCMessage.JSONRPC = rawResp.JSONRPC
 	resp.JSONRPCMessage.JSONRPCMessageIdentifier.ID = rawResp.ID
 	resp.Error = rawResp.Error
 
-	// If there's a result, try to decode it as a Task
+	// Leave the result undecoded; callers pick the type with resp.AsTask() and friends
 	if len(rawResp.Result) > 0 {
-		var task models.Task
-		if err := json.Unmarshal(rawResp.Result, &task); err != nil {
-			return fmt.Errorf("failed to decode task: %w", err)
-		}
-		resp.Result = &task
+		resp.Result = rawResp.Result
 	}
 
+	if err := resp.Validate(); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Error != nil {
+		return newA2AError(resp.Error, requestID)
+	}
 	return nil
 }
+
+// post sends a single JSON-RPC request to the agent
+func (c *Client) post(ctx context.Context, req models.JSONRPCRequest, requestID string) (*http.Response, error) {
+	httpReq, err := newJSONRequest(ctx, c.baseURL, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(httpReq, requestID)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return c.httpClient.Do(httpReq)
+}
+
+// newJSONRequest creates a POST request whose body is v encoded as JSON.
+// The body is encoded as it is sent rather than marshalled up front, so
+// that large file parts aren't held in memory twice.
+func newJSONRequest(ctx context.Context, url string, v any) (*http.Request, error) {
+	body := encodeJSON(v)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	// Lets the transport send the body again, e.g. to follow a redirect
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return encodeJSON(v), nil
+	}
+	return httpReq, nil
+}
+
+// encodeJSON returns a reader that streams v encoded as JSON. Encoding
+// errors are returned from Read. The transport closes the reader even when
+// a request fails, which stops the encoder.
+func encodeJSON(v any) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(json.NewEncoder(pw).Encode(v))
+	}()
+	return pr
+}
+
+// setHeaders applies the client's configured headers to an outgoing request,
+// along with its X-Request-ID and the W3C trace context of any OpenTelemetry
+// span in its context
+func (c *Client) setHeaders(req *http.Request, requestID string) {
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set(models.RequestIDHeader, requestID)
+	propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
+
+// endpoint returns the URL of a path beneath the base URL, such as the
+// agent card's well-known path
+func (c *Client) endpoint(path string) string {
+	base, query, _ := strings.Cut(c.baseURL, "?")
+	if query != "" {
+		query = "?" + query
+	}
+	return strings.TrimSuffix(base, "/") + path + query
+}
+
+// nextRequestID returns a fresh ID to correlate a request with its response
+func (c *Client) nextRequestID() string {
+	return strconv.FormatInt(c.lastID.Add(1), 10)
+}
@@ -1,12 +1,22 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"a2a/models"
+	"a2a/server"
 )
 
 func TestSendTask(t *testing.T) {
@@ -30,6 +40,9 @@ func TestSendTask(t *testing.T) {
 		resp := models.JSONRPCResponse{
 			JSONRPCMessage: models.JSONRPCMessage{
 				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: req.ID,
+				},
 			},
 			Result: task,
 		}
@@ -43,7 +56,7 @@ func TestSendTask(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "123",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{
 					Text: stringPtr("test message"),
@@ -57,9 +70,9 @@ func TestSendTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	task, ok := resp.Result.(*models.Task)
-	if !ok {
-		t.Fatal("expected result to be a Task")
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	if task.ID != "123" {
@@ -87,11 +100,17 @@ func TestGetTask(t *testing.T) {
 			Status: models.TaskStatus{
 				State: models.TaskStateCompleted,
 			},
+			Artifacts: []models.Artifact{
+				{Parts: []models.Part{{Text: stringPtr("result")}}},
+			},
 		}
 
 		resp := models.JSONRPCResponse{
 			JSONRPCMessage: models.JSONRPCMessage{
 				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: req.ID,
+				},
 			},
 			Result: task,
 		}
@@ -113,9 +132,9 @@ func TestGetTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	task, ok := resp.Result.(*models.Task)
-	if !ok {
-		t.Fatal("expected result to be a Task")
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	if task.ID != "123" {
@@ -125,6 +144,10 @@ func TestGetTask(t *testing.T) {
 	if task.Status.State != models.TaskStateCompleted {
 		t.Errorf("expected task status %s, got %s", models.TaskStateCompleted, task.Status.State)
 	}
+
+	if len(task.Artifacts) != 1 || *task.Artifacts[0].Parts[0].Text != "result" {
+		t.Errorf("expected one artifact with text result, got %v", task.Artifacts)
+	}
 }
 
 func TestCancelTask(t *testing.T) {
@@ -148,6 +171,9 @@ func TestCancelTask(t *testing.T) {
 		resp := models.JSONRPCResponse{
 			JSONRPCMessage: models.JSONRPCMessage{
 				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: req.ID,
+				},
 			},
 			Result: task,
 		}
@@ -167,9 +193,9 @@ func TestCancelTask(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	task, ok := resp.Result.(*models.Task)
-	if !ok {
-		t.Fatal("expected result to be a Task")
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	if task.ID != "123" {
@@ -216,6 +242,53 @@ func TestErrorResponse(t *testing.T) {
 	}
 }
 
+func TestTypedA2AError(t *testing.T) {
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, nil)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	_, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+
+	var a2aErr *A2AError
+	if !errors.As(err, &a2aErr) {
+		t.Fatalf("expected A2AError, got %T: %v", err, err)
+	}
+
+	if a2aErr.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("expected code %d, got %d", models.ErrorCodeTaskNotFound, a2aErr.Code)
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, nil)
+	var sent, echoed string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Get(models.RequestIDHeader)
+		a2aServer.ServeHTTP(w, r)
+		echoed = w.Header().Get(models.RequestIDHeader)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	_, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+
+	if sent == "" {
+		t.Fatal("expected the client to send a request ID")
+	}
+	if echoed != sent {
+		t.Errorf("expected the server to echo request ID %q, got %q", sent, echoed)
+	}
+
+	var a2aErr *A2AError
+	if !errors.As(err, &a2aErr) {
+		t.Fatalf("expected A2AError, got %T: %v", err, err)
+	}
+	if a2aErr.RequestID != sent {
+		t.Errorf("expected error to carry request ID %q, got %q", sent, a2aErr.RequestID)
+	}
+}
+
 func TestSendTaskStreaming(t *testing.T) {
 	// Create a channel to signal when all events have been sent
 	done := make(chan struct{})
@@ -231,16 +304,16 @@ func TestSendTaskStreaming(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if req.Method != "message/send" {
-			t.Errorf("expected method message/send, got %s", req.Method)
+		if req.Method != models.MethodMessageStream {
+			t.Errorf("expected method %s, got %s", models.MethodMessageStream, req.Method)
 		}
 
 		// Set response headers for streaming
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", "text/event-stream")
 		w.(http.Flusher).Flush()
 
 		// Send multiple events
-		events := []*models.Task{
+		events := []*models.TaskStatusUpdateEvent{
 			{
 				ID: "123",
 				Status: models.TaskStatus{
@@ -265,9 +338,11 @@ func TestSendTaskStreaming(t *testing.T) {
 				Result: event,
 			}
 
-			if err := json.NewEncoder(w).Encode(resp); err != nil {
+			data, err := json.Marshal(resp)
+			if err != nil {
 				t.Fatal(err)
 			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
 			w.(http.Flusher).Flush()
 		}
 
@@ -279,7 +354,7 @@ func TestSendTaskStreaming(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "123",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{
 					Text: stringPtr("test message"),
@@ -298,18 +373,13 @@ func TestSendTaskStreaming(t *testing.T) {
 	}()
 
 	// Collect and verify events
-	var events []models.Task
+	var events []*models.TaskStatusUpdateEvent
 	for event := range eventChan {
-		// The event should be a json.RawMessage that we need to unmarshal into a Task
-		rawMsg, ok := event.(json.RawMessage)
+		statusEvent, ok := event.(*models.TaskStatusUpdateEvent)
 		if !ok {
-			t.Fatalf("expected event to be a json.RawMessage, but was %v with type %T", event, event)
+			t.Fatalf("expected event to be a *models.TaskStatusUpdateEvent, but was %v with type %T", event, event)
 		}
-		var task models.Task
-		if err := json.Unmarshal(rawMsg, &task); err != nil {
-			t.Fatalf("failed to unmarshal task: %v", err)
-		}
-		events = append(events, task)
+		events = append(events, statusEvent)
 	}
 
 	// Check for any errors from streaming
@@ -335,6 +405,1117 @@ func TestSendTaskStreaming(t *testing.T) {
 	}
 }
 
-func stringPtr(s string) *string {
-	return &s
+func TestGetAgentCard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected method GET, got %s", r.Method)
+		}
+
+		if r.URL.Path != "/.well-known/agent.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		streaming := true
+		card := models.AgentCard{
+			Name:    "Test Agent",
+			URL:     "http://localhost:8080",
+			Version: "1.0.0",
+			Capabilities: models.AgentCapabilities{
+				Streaming: &streaming,
+			},
+			Skills: []models.AgentSkill{
+				{ID: "test-skill", Name: "Test Skill"},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(card)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	card, err := client.GetAgentCard()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if card.Name != "Test Agent" {
+		t.Errorf("expected agent name Test Agent, got %s", card.Name)
+	}
+
+	if card.Capabilities.Streaming == nil || !*card.Capabilities.Streaming {
+		t.Error("expected streaming capability to be true")
+	}
+
+	if len(card.Skills) != 1 || card.Skills[0].ID != "test-skill" {
+		t.Errorf("expected one skill test-skill, got %v", card.Skills)
+	}
+}
+
+func TestGetAgentCardNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.GetAgentCard()
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected StatusError, got %v", err)
+	}
+
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, statusErr.StatusCode)
+	}
+}
+
+func TestNewClientE(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", baseURL: "http://localhost:8080", want: "http://localhost:8080"},
+		{name: "trailing slash", baseURL: "https://agent.example.com/a2a/", want: "https://agent.example.com/a2a/"},
+		{name: "cleaned", baseURL: " HTTPS://Agent.Example.com//a2a#top ", want: "https://agent.example.com/a2a"},
+		{name: "missing scheme", baseURL: "localhost:8080", wantErr: true},
+		{name: "missing scheme and port", baseURL: "agent.example.com/a2a", wantErr: true},
+		{name: "unsupported scheme", baseURL: "ftp://agent.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientE(tt.baseURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error for %q", tt.baseURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client.baseURL != tt.want {
+				t.Errorf("expected base URL %q, got %q", tt.want, client.baseURL)
+			}
+		})
+	}
+}
+
+func TestGetAgentCardTrailingSlash(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewEncoder(w).Encode(models.AgentCard{Name: "Test Agent"})
+	}))
+	defer ts.Close()
+
+	client, err := NewClientE(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAgentCard(); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/.well-known/agent.json" {
+		t.Errorf("expected the well-known path without a double slash, got %q", path)
+	}
+}
+
+func TestSendTaskRoundTrip(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, handler)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	params := models.TaskSendParams{
+		ID: "123",
+		Message: models.Message{
+			Role: models.RoleUser,
+			Parts: []models.Part{
+				{
+					Text: stringPtr("test message"),
+				},
+			},
+		},
+	}
+
+	resp, err := client.SendTask(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.ID != "123" {
+		t.Errorf("expected task ID 123, got %s", task.ID)
+	}
+
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("expected task status %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
+
+func TestSendTaskStreamingArtifacts(t *testing.T) {
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{
+			ID: task.ID,
+			Artifact: models.Artifact{
+				Parts: []models.Part{{Text: stringPtr("chunk")}},
+			},
+		}
+		return nil
+	}
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, nil, server.WithStreamingHandler(streamingHandler))
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	params := models.TaskSendParams{
+		ID: "123",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("test message")}},
+		},
+	}
+
+	eventChan := make(chan any)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- client.SendTaskStreaming(params, eventChan)
+		close(eventChan)
+	}()
+
+	var artifacts []models.Artifact
+	var states []models.TaskState
+	for event := range eventChan {
+		switch e := event.(type) {
+		case *models.TaskArtifactUpdateEvent:
+			artifacts = append(artifacts, e.Artifact)
+		case *models.TaskStatusUpdateEvent:
+			states = append(states, e.Status.State)
+		default:
+			t.Fatalf("unexpected event type %T", event)
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(artifacts) != 1 || *artifacts[0].Parts[0].Text != "chunk" {
+		t.Errorf("expected one artifact event with text chunk, got %v", artifacts)
+	}
+	if len(states) != 3 || states[2] != models.TaskStateCompleted {
+		t.Errorf("expected submitted, working and completed status events, got %v", states)
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	})
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	if _, err := client.SendTask(models.TaskSendParams{
+		ID: "123",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("test message")}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	eventChan := make(chan any, 1)
+	params := models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}}
+	if err := client.Resubscribe(params, eventChan); err != nil {
+		t.Fatal(err)
+	}
+	close(eventChan)
+
+	var events []*models.TaskStatusUpdateEvent
+	for event := range eventChan {
+		statusEvent, ok := event.(*models.TaskStatusUpdateEvent)
+		if !ok {
+			t.Fatalf("expected a status event, got %T", event)
+		}
+		events = append(events, statusEvent)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Status.State != models.TaskStateCompleted || events[0].Final == nil || !*events[0].Final {
+		t.Errorf("expected final completed event, got %+v", events[0])
+	}
+
+	// Unknown tasks surface the JSON-RPC error
+	err := client.Resubscribe(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}}, eventChan)
+	if err == nil {
+		t.Fatal("expected error for unknown task")
+	}
+}
+
+// recordingTransport records outgoing requests before delegating to the default transport
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: requestID(r),
+				},
+			},
+			Result: &models.Task{ID: "123"},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	transport := &recordingTransport{}
+	client := NewClient(server.URL, WithHTTPClient(&http.Client{Transport: transport}))
+
+	if _, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.requests) != 1 {
+		t.Fatalf("expected 1 recorded request, got %d", len(transport.requests))
+	}
+	if transport.requests[0].Method != http.MethodPost {
+		t.Errorf("expected method POST, got %s", transport.requests[0].Method)
+	}
+	if transport.requests[0].URL.String() != server.URL {
+		t.Errorf("expected URL %s, got %s", server.URL, transport.requests[0].URL)
+	}
+}
+
+// largeFileParams returns message/send parameters carrying a file part of n base64 bytes
+func largeFileParams(n int) models.TaskSendParams {
+	return models.TaskSendParams{
+		ID: "123",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{File: models.FileContentBytes{Bytes: strings.Repeat("A", n)}}},
+		},
+	}
+}
+
+func TestSendTaskStreamsLargeBody(t *testing.T) {
+	const size = 8 << 20
+	var received int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     string                `json:"id"`
+			Params models.TaskSendParams `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if file, ok := req.Params.Message.Parts[0].File.(models.FileContentBytes); ok {
+			received = len(file.Bytes)
+		}
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: req.ID},
+			},
+			Result: models.Task{ID: "123"},
+		})
+	}))
+	defer ts.Close()
+
+	transport := &recordingTransport{}
+	client := NewClient(ts.URL, WithHTTPClient(&http.Client{Transport: transport}))
+	if _, err := client.SendTask(largeFileParams(size)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A body of unknown length was encoded while it was sent rather than
+	// marshalled into a buffer first
+	req := transport.requests[0]
+	if _, buffered := req.Body.(interface{ Len() int }); buffered || req.ContentLength != 0 {
+		t.Errorf("expected a streamed request body, got %T with length %d", req.Body, req.ContentLength)
+	}
+	if received != size {
+		t.Errorf("expected the agent to receive %d bytes of file content, got %d", size, received)
+	}
+}
+
+func BenchmarkSendTaskLargeFile(b *testing.B) {
+	const size = 8 << 20
+	// Discard the body so that only the client's allocations are counted,
+	// relying on the client numbering requests from 1
+	var lastID atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: fmt.Sprint(lastID.Add(1))},
+			},
+			Result: models.Task{ID: "123"},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	params := largeFileParams(size)
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.SendTask(params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	var followed int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed++
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Result: models.Task{ID: "123"},
+		})
+	}))
+	defer target.Close()
+
+	// A 307 keeps the method and body, so a followed redirect would succeed
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer proxy.Close()
+
+	params := models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}}
+
+	if _, err := NewClient(proxy.URL).GetTask(params); err == nil {
+		t.Error("expected an error for a cross-host redirect")
+	}
+	if followed != 0 {
+		t.Fatalf("expected the cross-host redirect not to be followed, target hit %d times", followed)
+	}
+
+	follow := func(req *http.Request, via []*http.Request) error { return nil }
+	if _, err := NewClient(proxy.URL, WithRedirectPolicy(follow)).GetTask(params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if followed != 1 {
+		t.Errorf("expected WithRedirectPolicy to follow the redirect, target hit %d times", followed)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	requestReceived := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestReceived)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-requestReceived
+		cancel()
+	}()
+
+	_, err := client.GetTaskContext(ctx, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSendTaskStreamingContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"result\":{\"id\":\"123\",\"status\":{\"state\":\"working\"}}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	eventChan := make(chan any)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- client.SendTaskStreamingContext(ctx, models.TaskSendParams{ID: "123"}, eventChan)
+	}()
+
+	// Stop consuming after the first event
+	<-eventChan
+	cancel()
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("streaming did not stop after the context was canceled")
+	}
+}
+
+func TestDoRequestPushConfigResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: requestID(r),
+				},
+			},
+			Result: models.PushNotificationConfig{
+				URL:   "https://example.com/notify",
+				Token: stringPtr("secret"),
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksPushNotificationGet,
+		Params: models.TaskIDParams{ID: "123"},
+	}
+
+	var resp models.JSONRPCResponse
+	if err := client.doRequest(context.Background(), req, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := resp.Result.(json.RawMessage); !ok {
+		t.Fatalf("expected raw result, got %T", resp.Result)
+	}
+
+	config, err := resp.AsPushConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.URL != "https://example.com/notify" {
+		t.Errorf("expected URL https://example.com/notify, got %s", config.URL)
+	}
+	if config.Token == nil || *config.Token != "secret" {
+		t.Errorf("expected token secret, got %v", config.Token)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestGetTaskRetry(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Result: models.Task{ID: "test-task"},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithRetry(3, time.Millisecond))
+	resp, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.ID != "test-task" {
+		t.Errorf("expected task ID test-task, got %s", task.ID)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestSendTaskNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithRetry(3, time.Millisecond))
+	if _, err := client.SendTask(models.TaskSendParams{ID: "test-task"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestAuthHeaders(t *testing.T) {
+	var gotAuth, gotKey []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		gotKey = append(gotKey, r.Header.Get("X-API-Key"))
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			return
+		}
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Result: models.Task{ID: "test-task"},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithBearerToken("secret"), WithAuthHeader("X-API-Key", "key"))
+	if _, err := client.SendTask(models.TaskSendParams{ID: "test-task"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.SendTaskStreaming(models.TaskSendParams{ID: "test-task"}, make(chan any, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(gotAuth) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(gotAuth))
+	}
+	for i := range gotAuth {
+		if gotAuth[i] != "Bearer secret" {
+			t.Errorf("expected Authorization Bearer secret, got %q", gotAuth[i])
+		}
+		if gotKey[i] != "key" {
+			t.Errorf("expected X-API-Key key, got %q", gotKey[i])
+		}
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	})
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	for _, id := range []string{"task-1", "task-2"} {
+		if _, err := client.SendTask(models.TaskSendParams{ID: id, Message: models.NewTextMessage(models.RoleUser, "Hello")}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	resp, err := client.ListTasks(models.ListTasksParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	page, err := resp.AsTaskList()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Tasks) != 1 || page.Tasks[0].ID != "task-2" {
+		t.Fatalf("expected newest task task-2 first, got %+v", page.Tasks)
+	}
+
+	resp, err = client.ListTasks(models.ListTasksParams{Limit: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	page, err = resp.AsTaskList()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Tasks) != 1 || page.Tasks[0].ID != "task-1" {
+		t.Errorf("expected task-1 on the second page, got %+v", page.Tasks)
+	}
+}
+
+// requestID returns the ID of the JSON-RPC request in r, for stub servers to echo
+func requestID(r *http.Request) interface{} {
+	var req models.JSONRPCRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	return req.ID
+}
+
+func TestRequestIDs(t *testing.T) {
+	var ids []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r)
+		ids = append(ids, id)
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: id},
+			},
+			Result: models.Task{ID: "test-task"},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	for range 2 {
+		if _, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(ids))
+	}
+	if ids[0] == nil || ids[0] == "" {
+		t.Errorf("expected a non-empty request ID, got %v", ids[0])
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("expected unique request IDs, got %v twice", ids[0])
+	}
+}
+
+func TestMismatchedResponseID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "someone-else"},
+			},
+			Result: models.Task{ID: "test-task"},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	_, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected ID mismatch error, got %v", err)
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Result: models.Task{ID: "test-task"},
+		})
+	}))
+	defer ts.Close()
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+	defer span.End()
+
+	client := NewClient(ts.URL)
+	if _, err := client.GetTaskContext(ctx, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Errorf("expected traceparent carrying trace %s, got %q", span.SpanContext().TraceID(), traceparent)
+	}
+}
+
+func TestArtifactAssembler(t *testing.T) {
+	index, yes, no := 0, true, false
+	assembler := NewArtifactAssembler()
+
+	if _, ok := assembler.Add(models.Artifact{Index: &index, Parts: []models.Part{models.NewTextPart("Hello, ")}, LastChunk: &no}); ok {
+		t.Fatal("expected first chunk to be held back")
+	}
+	artifact, ok := assembler.Add(models.Artifact{Index: &index, Parts: []models.Part{models.NewTextPart("world")}, Append: &yes, LastChunk: &yes})
+	if !ok {
+		t.Fatal("expected artifact after last chunk")
+	}
+	if len(artifact.Parts) != 2 || *artifact.Parts[0].Text != "Hello, " || *artifact.Parts[1].Text != "world" {
+		t.Errorf("expected reassembled parts, got %+v", artifact.Parts)
+	}
+	if artifact.Append != nil {
+		t.Errorf("expected append to be cleared, got %v", *artifact.Append)
+	}
+	if partial := assembler.Flush(); len(partial) != 0 {
+		t.Errorf("expected nothing pending, got %d artifacts", len(partial))
+	}
+}
+
+func TestReassembleArtifacts(t *testing.T) {
+	zero, one, yes, no := 0, 1, true, false
+
+	in := make(chan any, 6)
+	out := make(chan any, 6)
+	in <- &models.TaskStatusUpdateEvent{ID: "task-1", Status: models.TaskStatus{State: models.TaskStateWorking}}
+	in <- &models.TaskArtifactUpdateEvent{ID: "task-1", Artifact: models.Artifact{Index: &zero, Parts: []models.Part{models.NewTextPart("a")}, LastChunk: &no}}
+	in <- &models.TaskArtifactUpdateEvent{ID: "task-1", Artifact: models.Artifact{Index: &one, Parts: []models.Part{models.NewTextPart("x")}, LastChunk: &no}}
+	in <- &models.TaskArtifactUpdateEvent{ID: "task-1", Artifact: models.Artifact{Index: &zero, Parts: []models.Part{models.NewTextPart("b")}, Append: &yes, LastChunk: &yes}}
+	close(in)
+
+	ReassembleArtifacts(in, out)
+
+	var events []any
+	for e := range out {
+		events = append(events, e)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	if status, ok := events[0].(*models.TaskStatusUpdateEvent); !ok || status.Status.State != models.TaskStateWorking {
+		t.Errorf("expected status event to pass through, got %+v", events[0])
+	}
+
+	complete, ok := events[1].(*models.TaskArtifactUpdateEvent)
+	if !ok || complete.ID != "task-1" || len(complete.Artifact.Parts) != 2 || *complete.Artifact.Parts[1].Text != "b" {
+		t.Errorf("expected reassembled artifact 0, got %+v", events[1])
+	}
+	partial, ok := events[2].(*models.TaskArtifactUpdateEvent)
+	if !ok || *partial.Artifact.Index != 1 || len(partial.Artifact.Parts) != 1 {
+		t.Errorf("expected partial artifact 1 at stream end, got %+v", events[2])
+	}
+}
+
+func TestPushNotificationRoundTrip(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	card := models.AgentCard{Name: "Test Agent", Capabilities: models.AgentCapabilities{PushNotifications: boolPtr(true)}}
+	a2aServer := server.NewA2AServer(card, handler)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	if _, err := client.SendTask(models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	set, err := client.SetPushNotification(models.TaskPushNotificationConfig{
+		ID: "123",
+		PushNotificationConfig: models.PushNotificationConfig{
+			URL:   "https://example.com/notify",
+			Token: stringPtr("secret"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.URL != "https://example.com/notify" {
+		t.Errorf("expected stored URL to be echoed, got %q", set.URL)
+	}
+
+	got, err := client.GetPushNotification(models.TaskIDParams{ID: "123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.URL != "https://example.com/notify" || got.Token == nil || *got.Token != "secret" {
+		t.Errorf("expected config to round-trip, got %+v", got)
+	}
+
+	_, err = client.GetPushNotification(models.TaskIDParams{ID: "missing"})
+	var a2aErr *A2AError
+	if !errors.As(err, &a2aErr) || a2aErr.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("expected task not found for unknown task, got %v", err)
+	}
+}
+
+func TestDeleteTask(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, handler)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	if _, err := client.SendTask(models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.DeleteTask(models.TaskIDParams{ID: "123"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}})
+	var a2aErr *A2AError
+	if !errors.As(err, &a2aErr) || a2aErr.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("expected task not found after delete, got %v", err)
+	}
+
+	_, err = client.DeleteTask(models.TaskIDParams{ID: "123"})
+	if !errors.As(err, &a2aErr) || a2aErr.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("expected task not found deleting twice, got %v", err)
+	}
+}
+
+func TestSendTaskStreamingStopsOnFinalEvent(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, final := range []bool{false, true} {
+			data, _ := json.Marshal(models.SendTaskStreamingResponse{
+				Result: models.TaskStatusUpdateEvent{ID: "123", Status: models.TaskStatus{State: models.TaskStateCompleted}, Final: &final},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		w.(http.Flusher).Flush()
+
+		// Keep the connection open after the final event
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	client := NewClient(ts.URL)
+	eventChan := make(chan any, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.SendTaskStreaming(models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")}, eventChan)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected client to return after the final event")
+	}
+	if len(eventChan) != 2 {
+		t.Errorf("expected 2 events, got %d", len(eventChan))
+	}
+}
+
+func TestA2AErrorData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Error: &models.JSONRPCError{
+				Code:    int(models.ErrorCodeInvalidParams),
+				Message: "Invalid parameters",
+				Data:    map[string]interface{}{"field": "message.parts", "retryAfter": 5},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	_, err := client.SendTask(models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")})
+
+	var a2aErr *A2AError
+	if !errors.As(err, &a2aErr) {
+		t.Fatalf("expected A2AError, got %T: %v", err, err)
+	}
+	data, ok := a2aErr.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error data object, got %T", a2aErr.Data)
+	}
+	if data["field"] != "message.parts" || data["retryAfter"] != float64(5) {
+		t.Errorf("expected error data to be preserved, got %v", data)
+	}
+}
+
+func TestSendTaskAndWait(t *testing.T) {
+	var gets atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		state := models.TaskStateWorking
+		if req.Method == models.MethodTasksGet && gets.Add(1) == 2 {
+			state = models.TaskStateCompleted
+		}
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: req.ID},
+			},
+			Result: models.Task{ID: "123", Status: models.TaskStatus{State: state}},
+		})
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := NewClient(ts.URL)
+	task, err := client.SendTaskAndWait(ctx, models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("expected state completed, got %s", task.Status.State)
+	}
+	if got := gets.Load(); got != 2 {
+		t.Errorf("expected 2 polls, got %d", got)
+	}
+}
+
+func TestSendTaskAndWaitContextExpires(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.JSONRPCResponse{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC:                  "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: requestID(r)},
+			},
+			Result: models.Task{ID: "123", Status: models.TaskStatus{State: models.TaskStateWorking}},
+		})
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(ts.URL)
+	_, err := client.SendTaskAndWait(ctx, models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "test message")}, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestInvalidResponseRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"both set", `{"jsonrpc":"2.0","id":%q,"result":{"id":"123"},"error":{"code":-32603,"message":"Internal error"}}`},
+		{"neither set", `{"jsonrpc":"2.0","id":%q}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, tt.body, requestID(r))
+			}))
+			defer ts.Close()
+
+			client := NewClient(ts.URL)
+			_, err := client.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "123"}})
+			if err == nil || !strings.Contains(err.Error(), "invalid response") {
+				t.Errorf("expected invalid response error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInputRequiredPrompt(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		prompt := models.NewTextMessage(models.RoleAgent, "Which city should I book the flight to?")
+		task.Status.State = models.TaskStateInputRequired
+		task.Status.Message = &prompt
+		return task, nil
+	}
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, handler)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	resp, err := client.SendTask(models.TaskSendParams{ID: "123", Message: models.NewTextMessage(models.RoleUser, "Book me a flight")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prompt, ok := task.InputPrompt()
+	if !ok || prompt == nil {
+		t.Fatalf("expected an input prompt, got state %s", task.Status.State)
+	}
+	if prompt.Role != models.RoleAgent || prompt.Text() != "Which city should I book the flight to?" {
+		t.Errorf("expected agent prompt, got %s: %q", prompt.Role, prompt.Text())
+	}
+}
+
+func TestPing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/healthz" {
+			t.Errorf("expected GET /healthz, got %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok","version":"1.2.0"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	health, err := client.Ping()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if health.Status != "ok" || health.Version != "1.2.0" {
+		t.Errorf("expected healthy agent at version 1.2.0, got %+v", health)
+	}
+}
+
+func TestPingUnhealthy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	_, err := client.Ping()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status error 503, got %v", err)
+	}
 }
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"fmt"
+
+	"a2a/models"
+)
+
+// A2AError is returned when the agent responds with a JSON-RPC error.
+// Use errors.As to inspect the code, e.g. to detect a task that was not found.
+type A2AError struct {
+	Code    int
+	Message string
+	// Data holds any structured details the agent attached to the error,
+	// decoded from JSON
+	Data interface{}
+	// RequestID is the X-Request-ID the request was sent with, for finding
+	// it in the agent's logs
+	RequestID string
+}
+
+func (e *A2AError) Error() string {
+	return fmt.Sprintf("A2A error: %s (code: %d)", e.Message, e.Code)
+}
+
+func newA2AError(e *models.JSONRPCError, requestID string) *A2AError {
+	return &A2AError{
+		Code:      e.Code,
+		Message:   e.Message,
+		Data:      e.Data,
+		RequestID: requestID,
+	}
+}
+
+// StatusError is returned when the agent responds with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"time"
+
+	"a2a/models"
+)
+
+// WithStreamReconnect resumes streams that drop before the task's final
+// event. The client resubscribes to the task up to maxRetries times, waiting
+// between attempts as WithRetry does, and sends the ID of the last event it
+// received in the Last-Event-ID header so that the agent can replay what was
+// missed. Replayed events the client has already delivered are skipped;
+// events without an ID cannot be told apart and may be delivered again. By
+// default a dropped stream simply ends.
+func WithStreamReconnect(maxRetries int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.reconnect = retryPolicy{maxAttempts: maxRetries, baseDelay: baseDelay}
+	}
+}
+
+// streamState is what a stream remembers across reconnects
+type streamState struct {
+	// taskID is the task the stream follows, known once an event arrives
+	taskID string
+	// lastEventID is the ID of the last event delivered
+	lastEventID string
+	// seen holds the IDs of every event delivered
+	seen map[string]bool
+}
+
+// received records an event delivered to the caller
+func (st *streamState) received(eventID string, event models.StreamEvent) {
+	switch e := event.(type) {
+	case *models.TaskStatusUpdateEvent:
+		st.taskID = e.ID
+	case *models.TaskArtifactUpdateEvent:
+		st.taskID = e.ID
+	}
+	if eventID != "" {
+		st.seen[eventID] = true
+		st.lastEventID = eventID
+	}
+}
+
+// resubscribeRequest returns the request that reattaches to a task's stream
+func resubscribeRequest(taskID string) models.JSONRPCRequest {
+	return models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodTasksResubscribe,
+		Params: models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: taskID}},
+	}
+}
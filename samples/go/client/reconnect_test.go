@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// writeEvent writes a status update for task 123 as an SSE frame with the given ID
+func writeEvent(w http.ResponseWriter, id string, state models.TaskState, final bool) {
+	fmt.Fprintf(w, "id: %s\ndata: {\"jsonrpc\":\"2.0\",\"result\":{\"id\":\"123\",\"status\":{\"state\":%q},\"final\":%t}}\n\n", id, state, final)
+	w.(http.Flusher).Flush()
+}
+
+func TestStreamReconnect(t *testing.T) {
+	var requests atomic.Int32
+	var lastEventID, resubscribeMethod string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		if requests.Add(1) == 1 {
+			writeEvent(w, "1", models.TaskStateSubmitted, false)
+			writeEvent(w, "2", models.TaskStateWorking, false)
+			// Drop the connection mid-task
+			panic(http.ErrAbortHandler)
+		}
+
+		resubscribeMethod = req.Method
+		lastEventID = r.Header.Get(models.LastEventIDHeader)
+		// The agent replays the last event before carrying on
+		writeEvent(w, "2", models.TaskStateWorking, false)
+		writeEvent(w, "3", models.TaskStateCompleted, true)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithStreamReconnect(3, time.Millisecond))
+	eventChan := make(chan any, 10)
+	err := client.SendTaskStreaming(models.TaskSendParams{
+		ID:      "123",
+		Message: models.NewTextMessage(models.RoleUser, "test message"),
+	}, eventChan)
+	close(eventChan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resubscribeMethod != models.MethodTasksResubscribe || lastEventID != "2" {
+		t.Errorf("expected to resubscribe after event 2, got method %q with Last-Event-ID %q", resubscribeMethod, lastEventID)
+	}
+
+	var states []models.TaskState
+	for event := range eventChan {
+		states = append(states, event.(*models.TaskStatusUpdateEvent).Status.State)
+	}
+	want := []models.TaskState{models.TaskStateSubmitted, models.TaskStateWorking, models.TaskStateCompleted}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("expected each event exactly once %v, got %v", want, states)
+	}
+}
+
+func TestStreamReconnectGivesUp(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		n := requests.Add(1)
+		writeEvent(w, fmt.Sprint(n), models.TaskStateWorking, false)
+		panic(http.ErrAbortHandler)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithStreamReconnect(2, time.Millisecond))
+	stream := client.OpenStream(context.Background(), models.TaskSendParams{
+		ID:      "123",
+		Message: models.NewTextMessage(models.RoleUser, "test message"),
+	})
+	for range stream.Events() {
+	}
+
+	if stream.Err() == nil {
+		t.Error("expected an error once reconnects were exhausted")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected the original request and 2 reconnects, got %d requests", n)
+	}
+}
+
+func TestStreamWithoutReconnect(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvent(w, "1", models.TaskStateWorking, false)
+		panic(http.ErrAbortHandler)
+	}))
+	defer ts.Close()
+
+	eventChan := make(chan any, 10)
+	err := NewClient(ts.URL).SendTaskStreaming(models.TaskSendParams{ID: "123"}, eventChan)
+	if err == nil {
+		t.Error("expected the dropped stream to fail")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected no reconnects by default, got %d requests", n)
+	}
+}
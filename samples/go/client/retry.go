@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"a2a/models"
+)
+
+// idempotentMethods lists the JSON-RPC methods that are safe to retry.
+// Sending a message or canceling a task may have side effects on the agent,
+// so only reads are retried.
+var idempotentMethods = map[string]bool{
+	models.MethodTasksGet:                 true,
+	models.MethodTasksList:                true,
+	models.MethodTasksPushNotificationGet: true,
+}
+
+// retryPolicy controls how idempotent requests are retried
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// WithRetry retries idempotent requests (tasks/get, tasks/list) up to
+// maxAttempts times in total when the agent is unreachable or responds with
+// 503 Service Unavailable. The delay between attempts starts at baseDelay and doubles
+// after each failure, with jitter. Streaming requests are never retried; see
+// WithStreamReconnect for resuming dropped streams.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retryPolicy{maxAttempts: maxAttempts, baseDelay: baseDelay}
+	}
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// backoff returns the delay before the attempt following the given one:
+// baseDelay doubled per attempt, with the upper half randomized
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay << attempt
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// wait sleeps before the next attempt, returning early if ctx is done
+func (p retryPolicy) wait(ctx context.Context, attempt int) error {
+	t := time.NewTimer(p.backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single Server-Sent Events frame
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// sseReader parses Server-Sent Events frames from a stream
+type sseReader struct {
+	r *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event with a data payload, or io.EOF at the end of
+// the stream. A frame cut off by the end of the stream is discarded, as the
+// SSE spec requires, so a dropped connection never delivers half an event.
+func (s *sseReader) Next() (*sseEvent, error) {
+	var event sseEvent
+	var data []string
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			// At io.EOF, whatever is left was never terminated by a blank line
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line dispatches the event; frames without data are skipped
+			if len(data) > 0 {
+				event.Data = strings.Join(data, "\n")
+				return &event, nil
+			}
+			event = sseEvent{}
+			continue
+		}
+
+		// Lines starting with a colon are comments
+		if !strings.HasPrefix(line, ":") {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "data":
+				data = append(data, value)
+			case "event":
+				event.Event = value
+			case "id":
+				event.ID = value
+			}
+		}
+	}
+}
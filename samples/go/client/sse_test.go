@@ -0,0 +1,47 @@
+package client
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSSEReader(t *testing.T) {
+	stream := ": keepalive\n\n" +
+		"data: {\"a\":1}\n\n" +
+		"id: 7\nevent: update\ndata: line one\ndata: line two\n\n" +
+		"data:no-space\r\n\r\n" +
+		"data: truncated\n"
+
+	reader := newSSEReader(strings.NewReader(stream))
+
+	want := []sseEvent{
+		{Data: `{"a":1}`},
+		{ID: "7", Event: "update", Data: "line one\nline two"},
+		{Data: "no-space"},
+	}
+
+	for i, w := range want {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		if *got != w {
+			t.Errorf("event %d: expected %+v, got %+v", i, w, *got)
+		}
+	}
+
+	// The last frame was cut off before its blank line, so it is dropped
+	if event, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %+v, %v", event, err)
+	}
+}
+
+func TestSSEReaderDiscardsTruncatedFrame(t *testing.T) {
+	for _, stream := range []string{"data: half", "id: 3\ndata: half\n", "data: half\r\n"} {
+		reader := newSSEReader(strings.NewReader(stream))
+		if event, err := reader.Next(); err != io.EOF {
+			t.Errorf("%q: expected io.EOF, got %+v, %v", stream, event, err)
+		}
+	}
+}
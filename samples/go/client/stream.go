@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"a2a/models"
+)
+
+// Stream is an open message/stream request. Range over Events until it is
+// closed, then check Err to learn why the stream ended.
+type Stream struct {
+	events chan models.StreamEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+	closed atomic.Bool
+	err    error
+}
+
+// OpenStream sends a task message and streams the agent's updates. The
+// stream ends after the final status event, when ctx is done, on error, or
+// when Close is called.
+func (c *Client) OpenStream(ctx context.Context, params models.TaskSendParams) *Stream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{
+		events: make(chan models.StreamEvent),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	req := models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Method: models.MethodMessageStream,
+		Params: params,
+	}
+
+	go func() {
+		// done closes before events so that Err is ready as soon as the
+		// caller sees the end of the stream
+		defer close(s.events)
+		defer close(s.done)
+		defer cancel()
+
+		err := c.doStreamingRequest(ctx, req, func(ctx context.Context, event models.StreamEvent) error {
+			select {
+			case s.events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		// Stopping the stream on purpose is not an error
+		if s.closed.Load() && errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		s.err = err
+	}()
+
+	return s
+}
+
+// Events returns the stream's events, each a *models.TaskStatusUpdateEvent
+// or *models.TaskArtifactUpdateEvent. The channel is closed when the stream
+// ends.
+func (s *Stream) Events() <-chan models.StreamEvent {
+	return s.events
+}
+
+// Err returns the error that ended the stream, or nil if it ended normally
+// or was closed. It is only meaningful once Events has been closed.
+func (s *Stream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream, releasing its connection, and waits for it to end.
+// It is safe to call more than once.
+func (s *Stream) Close() error {
+	s.closed.Store(true)
+	s.cancel()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"a2a/models"
+	"a2a/server"
+)
+
+func TestOpenStream(t *testing.T) {
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{
+			ID:       task.ID,
+			Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr("chunk")}}},
+		}
+		return nil
+	}
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, nil, server.WithStreamingHandler(streamingHandler))
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	stream := client.OpenStream(context.Background(), models.TaskSendParams{
+		ID:      "123",
+		Message: models.NewTextMessage(models.RoleUser, "test message"),
+	})
+	defer stream.Close()
+
+	var states []models.TaskState
+	var artifacts int
+	for event := range stream.Events() {
+		switch e := event.(type) {
+		case *models.TaskStatusUpdateEvent:
+			states = append(states, e.Status.State)
+		case *models.TaskArtifactUpdateEvent:
+			artifacts++
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if artifacts != 1 {
+		t.Errorf("expected 1 artifact event, got %d", artifacts)
+	}
+	if len(states) == 0 || states[len(states)-1] != models.TaskStateCompleted {
+		t.Errorf("expected stream to end with a completed status, got %v", states)
+	}
+}
+
+func TestOpenStreamError(t *testing.T) {
+	a2aServer := server.NewA2AServer(models.AgentCard{Name: "Test Agent"}, nil)
+	ts := httptest.NewServer(a2aServer)
+	defer ts.Close()
+
+	// A message without parts is rejected before the stream starts
+	stream := NewClient(ts.URL).OpenStream(context.Background(), models.TaskSendParams{
+		ID:      "123",
+		Message: models.Message{Role: models.RoleUser},
+	})
+	for range stream.Events() {
+		t.Error("expected no events")
+	}
+
+	var a2aErr *A2AError
+	if !errors.As(stream.Err(), &a2aErr) || a2aErr.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("expected an invalid params A2AError, got %v", stream.Err())
+	}
+}
+
+func TestOpenStreamClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"result\":{\"id\":\"123\",\"status\":{\"state\":\"working\"}}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	stream := NewClient(ts.URL).OpenStream(context.Background(), models.TaskSendParams{ID: "123"})
+	<-stream.Events()
+
+	closed := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not stop the stream")
+	}
+
+	if _, ok := <-stream.Events(); ok {
+		t.Error("expected events to be closed")
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("expected no error after Close, got %v", err)
+	}
+}
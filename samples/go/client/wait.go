@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"a2a/models"
+)
+
+// SendTaskAndWait sends a task and, if the agent has not finished it yet,
+// polls tasks/get every pollInterval until the task completes, fails or is
+// canceled, returning the task in that state. It gives up when ctx is done.
+// A task waiting for input is not finished, so bound ctx when the agent may
+// ask for more.
+func (c *Client) SendTaskAndWait(ctx context.Context, params models.TaskSendParams, pollInterval time.Duration) (*models.Task, error) {
+	resp, err := c.SendTaskContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	task, err := resp.AsTask()
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	query := models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: task.ID}}
+	for !task.Status.State.IsTerminal() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		resp, err := c.GetTaskContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if task, err = resp.AsTask(); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
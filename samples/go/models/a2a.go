@@ -1,5 +1,10 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 // TaskState represents the state of a task within the A2A protocol
 type TaskState string
 
@@ -13,6 +18,38 @@ const (
 	TaskStateUnknown       TaskState = "unknown"
 )
 
+// IsTerminal reports whether the state is final and can no longer change
+func (s TaskState) IsTerminal() bool {
+	switch s {
+	case TaskStateCompleted, TaskStateCanceled, TaskStateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransitionTo reports whether a task in state s may move to next. Tasks
+// start submitted, are worked on, may pause for input and end in a terminal
+// state, which never changes. A task in the unknown state may move to any
+// other state.
+func (s TaskState) CanTransitionTo(next TaskState) bool {
+	switch s {
+	case TaskStateSubmitted, TaskStateWorking:
+		switch next {
+		case TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed:
+			return true
+		}
+	case TaskStateInputRequired:
+		switch next {
+		case TaskStateWorking, TaskStateInputRequired, TaskStateCanceled, TaskStateFailed:
+			return true
+		}
+	case TaskStateUnknown:
+		return next != TaskStateUnknown && next != ""
+	}
+	return false
+}
+
 // AgentAuthentication defines the authentication schemes and credentials for an agent
 type AgentAuthentication struct {
 	// Schemes is a list of supported authentication schemes
@@ -82,3 +119,45 @@ type AgentCard struct {
 	// Skills is the list of specific skills offered by the agent
 	Skills []AgentSkill `json:"skills"`
 }
+
+// Validate checks that the card has the fields the protocol requires: a
+// name, URL and version, and at least one skill, each with a unique ID and a
+// name. All problems found are reported together.
+func (c AgentCard) Validate() error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, errors.New("agent card has no name"))
+	}
+	if c.URL == "" {
+		errs = append(errs, errors.New("agent card has no URL"))
+	}
+	if c.Version == "" {
+		errs = append(errs, errors.New("agent card has no version"))
+	}
+	if len(c.Skills) == 0 {
+		errs = append(errs, errors.New("agent card has no skills"))
+	}
+
+	seen := make(map[string]bool)
+	for i, skill := range c.Skills {
+		switch {
+		case skill.ID == "":
+			errs = append(errs, fmt.Errorf("skill %d has no ID", i))
+		case seen[skill.ID]:
+			errs = append(errs, fmt.Errorf("duplicate skill ID %q", skill.ID))
+		}
+		seen[skill.ID] = true
+		if skill.Name == "" {
+			errs = append(errs, fmt.Errorf("skill %d has no name", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthStatus is the body of an agent's health check response
+type HealthStatus struct {
+	// Status is "ok" while the agent is serving
+	Status string `json:"status"`
+	// Version is the agent version from its card
+	Version string `json:"version,omitempty"`
+}
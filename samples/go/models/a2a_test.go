@@ -0,0 +1,86 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAgentCardValidate(t *testing.T) {
+	valid := func() AgentCard {
+		return AgentCard{
+			Name:    "Test Agent",
+			URL:     "http://localhost:8080",
+			Version: "1.0.0",
+			Skills:  []AgentSkill{{ID: "echo", Name: "Echo"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*AgentCard)
+		wantErr string
+	}{
+		{"valid", func(c *AgentCard) {}, ""},
+		{"missing name", func(c *AgentCard) { c.Name = "" }, "no name"},
+		{"missing URL", func(c *AgentCard) { c.URL = "" }, "no URL"},
+		{"missing version", func(c *AgentCard) { c.Version = "" }, "no version"},
+		{"no skills", func(c *AgentCard) { c.Skills = nil }, "no skills"},
+		{"skill without ID", func(c *AgentCard) { c.Skills[0].ID = "" }, "skill 0 has no ID"},
+		{"skill without name", func(c *AgentCard) { c.Skills[0].Name = "" }, "skill 0 has no name"},
+		{"duplicate skill IDs", func(c *AgentCard) {
+			c.Skills = append(c.Skills, AgentSkill{ID: "echo", Name: "Echo again"})
+		}, `duplicate skill ID "echo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := valid()
+			tt.modify(&card)
+
+			err := card.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTaskStateCanTransitionTo(t *testing.T) {
+	states := []TaskState{
+		TaskStateSubmitted,
+		TaskStateWorking,
+		TaskStateInputRequired,
+		TaskStateCompleted,
+		TaskStateCanceled,
+		TaskStateFailed,
+		TaskStateUnknown,
+	}
+	legal := map[TaskState][]TaskState{
+		TaskStateSubmitted:     {TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed},
+		TaskStateWorking:       {TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed},
+		TaskStateInputRequired: {TaskStateWorking, TaskStateInputRequired, TaskStateCanceled, TaskStateFailed},
+		TaskStateCompleted:     nil,
+		TaskStateCanceled:      nil,
+		TaskStateFailed:        nil,
+		TaskStateUnknown:       {TaskStateSubmitted, TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed},
+	}
+
+	for _, from := range states {
+		for _, to := range states {
+			want := slices.Contains(legal[from], to)
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+		if from.CanTransitionTo("") {
+			t.Errorf("Expected %s not to transition to an empty state", from)
+		}
+	}
+}
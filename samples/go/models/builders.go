@@ -0,0 +1,32 @@
+package models
+
+import "encoding/base64"
+
+// NewTextPart returns a text part
+func NewTextPart(text string) Part {
+	return Part{Type: PartTypeText, Text: &text}
+}
+
+// NewFilePartBytes returns a file part carrying data inline as base64.
+// Empty name or mime leave the corresponding field unset.
+func NewFilePartBytes(name, mime string, data []byte) Part {
+	file := FileContentBytes{Bytes: base64.StdEncoding.EncodeToString(data)}
+	if name != "" {
+		file.Name = &name
+	}
+	if mime != "" {
+		file.MimeType = &mime
+	}
+	return Part{Type: PartTypeFile, File: file}
+}
+
+// NewDataPart returns a part carrying structured data
+func NewDataPart(data map[string]interface{}) Part {
+	return Part{Type: PartTypeData, Data: data}
+}
+
+// NewTextMessage returns a message with a single text part. role is
+// RoleUser or RoleAgent.
+func NewTextMessage(role, text string) Message {
+	return Message{Role: role, Parts: []Part{NewTextPart(text)}}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuilderJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "text message",
+			value: NewTextMessage(RoleUser, "Hello"),
+			want:  `{"role":"user","parts":[{"type":"text","text":"Hello"}]}`,
+		},
+		{
+			name:  "file part",
+			value: NewFilePartBytes("hello.txt", "text/plain", []byte("hello")),
+			want:  `{"type":"file","file":{"name":"hello.txt","mimeType":"text/plain","bytes":"aGVsbG8="}}`,
+		},
+		{
+			name:  "file part without name or MIME type",
+			value: NewFilePartBytes("", "", []byte("hello")),
+			want:  `{"type":"file","file":{"bytes":"aGVsbG8="}}`,
+		},
+		{
+			name:  "data part",
+			value: NewDataPart(map[string]interface{}{"answer": 42}),
+			want:  `{"type":"data","data":{"answer":42}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Failed to marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewFilePartBytesRoundTrip(t *testing.T) {
+	data, mimeType, err := NewFilePartBytes("image.png", "image/png", []byte{0x89, 'P', 'N', 'G'}).FileBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "\x89PNG" || mimeType != "image/png" {
+		t.Errorf("Expected PNG bytes and image/png, got %q and %s", data, mimeType)
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxFileBytes caps how much content FileBytes reads from a URI
+	maxFileBytes = 16 << 20
+	// fileFetchTimeout bounds URI fetches made by FileBytes
+	fileFetchTimeout = 30 * time.Second
+)
+
+// FileBytes returns the content of a file part and its MIME type, decoding
+// inline base64 bytes or fetching the URI over HTTP. URI fetches time out
+// after 30 seconds and fail for content larger than 16 MiB.
+func (p Part) FileBytes() ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fileFetchTimeout)
+	defer cancel()
+	return p.FileBytesContext(ctx)
+}
+
+// FileBytesContext is like FileBytes but fetches URIs with ctx, which
+// controls cancellation and deadlines instead of the default timeout. If ctx
+// carries a URIPolicy, as task handler contexts do, URIs it forbids fail with
+// ErrURINotAllowed.
+func (p Part) FileBytesContext(ctx context.Context) ([]byte, string, error) {
+	switch file := p.File.(type) {
+	case FileContentBytes:
+		data, err := base64.StdEncoding.DecodeString(file.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode file bytes: %w", err)
+		}
+		return data, stringValue(file.MimeType), nil
+	case FileContentURI:
+		return fetchFile(ctx, file)
+	case nil:
+		return nil, "", errors.New("part has no file content")
+	default:
+		return nil, "", fmt.Errorf("unsupported file content %T", file)
+	}
+}
+
+// fetchFile downloads a URI file, preferring the declared MIME type over the
+// one the server reports
+func fetchFile(ctx context.Context, file FileContentURI) ([]byte, string, error) {
+	client := http.DefaultClient
+	if policy, ok := uriPolicyFromContext(ctx); ok {
+		if err := policy.Check(file.URI); err != nil {
+			return nil, "", err
+		}
+		client = policy.httpClient()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", file.URI, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch file: unexpected status code: %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit to tell a file of exactly the limit from a larger one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFileBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxFileBytes {
+		return nil, "", fmt.Errorf("file exceeds %d bytes", maxFileBytes)
+	}
+
+	mimeType := stringValue(file.MimeType)
+	if mimeType == "" {
+		mimeType = resp.Header.Get("Content-Type")
+	}
+	return data, mimeType, nil
+}
+
+// stringValue dereferences an optional string
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
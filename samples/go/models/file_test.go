@@ -0,0 +1,93 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartFileBytes(t *testing.T) {
+	mimeType := "text/plain"
+	part := Part{File: FileContentBytes{
+		FileContentBase: FileContentBase{MimeType: &mimeType},
+		Bytes:           "aGVsbG8=",
+	}}
+
+	data, gotMime, err := part.FileBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("Expected hello, got %q", data)
+	}
+	if gotMime != mimeType {
+		t.Errorf("Expected MIME type %s, got %s", mimeType, gotMime)
+	}
+}
+
+func TestPartFileBytesMalformed(t *testing.T) {
+	part := Part{File: FileContentBytes{Bytes: "not base64!"}}
+
+	if _, _, err := part.FileBytes(); err == nil {
+		t.Error("Expected error for malformed base64, got nil")
+	}
+}
+
+func TestPartFileBytesURI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png data"))
+	}))
+	defer ts.Close()
+
+	part := Part{File: FileContentURI{URI: ts.URL + "/image.png"}}
+	data, mimeType, err := part.FileBytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "png data" {
+		t.Errorf("Expected png data, got %q", data)
+	}
+	if mimeType != "image/png" {
+		t.Errorf("Expected MIME type image/png, got %s", mimeType)
+	}
+}
+
+func TestPartFileBytesURIErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/large":
+			w.Write(bytes.Repeat([]byte("a"), maxFileBytes+1))
+		case "/slow":
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/missing", "/large"} {
+		part := Part{File: FileContentURI{URI: ts.URL + path}}
+		if _, _, err := part.FileBytes(); err == nil {
+			t.Errorf("Expected error fetching %s, got nil", path)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	part := Part{File: FileContentURI{URI: ts.URL + "/slow"}}
+	if _, _, err := part.FileBytesContext(ctx); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+}
+
+func TestPartFileBytesNotFile(t *testing.T) {
+	text := "hello"
+	if _, _, err := (Part{Text: &text}).FileBytes(); err == nil {
+		t.Error("Expected error for a text part, got nil")
+	}
+}
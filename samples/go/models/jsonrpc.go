@@ -1,10 +1,15 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+)
+
 // JSONRPCMessageIdentifier represents the base interface for identifying JSON-RPC messages
 type JSONRPCMessageIdentifier struct {
 	// ID is the request identifier. Can be a string, number, or null.
 	// Responses must have the same ID as the request they relate to.
-	// Notifications (requests without an expected response) should omit the ID or use null.
+	// Notifications (requests without an expected response) omit the ID; a null ID still gets a response.
 	ID interface{} `json:"id,omitempty"`
 }
 
@@ -44,3 +49,60 @@ type JSONRPCResponse struct {
 	// Required on failure. Should be null or omitted if the request was successful.
 	Error *JSONRPCError `json:"error,omitempty"`
 }
+
+// Validate checks that exactly one of Result and Error is set, as JSON-RPC
+// requires of every response
+func (r *JSONRPCResponse) Validate() error {
+	switch {
+	case r.Result != nil && r.Error != nil:
+		return errors.New("response has both a result and an error")
+	case r.Result == nil && r.Error == nil:
+		return errors.New("response has neither a result nor an error")
+	}
+	return nil
+}
+
+// DecodeResult unmarshals the response result into v. The result may be raw
+// JSON, as returned by the client, or an already-decoded value.
+func (r *JSONRPCResponse) DecodeResult(v interface{}) error {
+	var data []byte
+	switch result := r.Result.(type) {
+	case nil:
+		return errors.New("response has no result")
+	case json.RawMessage:
+		data = result
+	default:
+		var err error
+		if data, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// AsTask decodes the response result as a Task
+func (r *JSONRPCResponse) AsTask() (*Task, error) {
+	var task Task
+	if err := r.DecodeResult(&task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// AsPushConfig decodes the response result as a PushNotificationConfig
+func (r *JSONRPCResponse) AsPushConfig() (*PushNotificationConfig, error) {
+	var config PushNotificationConfig
+	if err := r.DecodeResult(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// AsTaskList decodes the response result as a TaskList
+func (r *JSONRPCResponse) AsTaskList() (*TaskList, error) {
+	var list TaskList
+	if err := r.DecodeResult(&list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
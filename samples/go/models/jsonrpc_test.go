@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONRPCResponseDecodeResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+	}{
+		{"raw JSON", json.RawMessage(`{"id":"123","status":{"state":"completed"}}`)},
+		{"decoded map", map[string]interface{}{"id": "123", "status": map[string]interface{}{"state": "completed"}}},
+		{"typed value", &Task{ID: "123", Status: TaskStatus{State: TaskStateCompleted}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := JSONRPCResponse{Result: tt.result}
+
+			task, err := resp.AsTask()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if task.ID != "123" || task.Status.State != TaskStateCompleted {
+				t.Errorf("Unexpected task %+v", task)
+			}
+		})
+	}
+
+	if _, err := (&JSONRPCResponse{}).AsTask(); err == nil {
+		t.Error("Expected error for missing result")
+	}
+}
+
+func TestJSONRPCResponseValidate(t *testing.T) {
+	rpcErr := &JSONRPCError{Code: int(ErrorCodeInternalError), Message: "Internal error"}
+	tests := []struct {
+		name    string
+		resp    JSONRPCResponse
+		wantErr bool
+	}{
+		{"result only", JSONRPCResponse{Result: &Task{ID: "123"}}, false},
+		{"error only", JSONRPCResponse{Error: rpcErr}, false},
+		{"both set", JSONRPCResponse{Result: &Task{ID: "123"}, Error: rpcErr}, true},
+		{"neither set", JSONRPCResponse{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.resp.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,5 +1,36 @@
 package models
 
+import "crypto/rand"
+
+// A2A JSON-RPC method names
+const (
+	MethodMessageSend      = "message/send"
+	MethodMessageStream    = "message/stream"
+	MethodTasksGet         = "tasks/get"
+	MethodTasksCancel      = "tasks/cancel"
+	MethodTasksResubscribe = "tasks/resubscribe"
+	MethodTasksList        = "tasks/list"
+	MethodTasksDelete      = "tasks/delete"
+
+	MethodTasksPushNotificationSet = "tasks/pushNotification/set"
+	MethodTasksPushNotificationGet = "tasks/pushNotification/get"
+)
+
+// RequestIDHeader is the HTTP header that carries a correlation ID for a
+// request. Servers echo it in the response so client and server logs can be
+// matched up.
+const RequestIDHeader = "X-Request-ID"
+
+// LastEventIDHeader is the Server-Sent Events header a client sends when
+// reconnecting to a stream, naming the last event it received so that the
+// server can resume after it
+const LastEventIDHeader = "Last-Event-ID"
+
+// NewRequestID returns a random ID suitable for RequestIDHeader
+func NewRequestID() string {
+	return rand.Text()
+}
+
 // TaskSendParams represents the parameters for sending a task message
 type TaskSendParams struct {
 	// ID is the unique identifier for the task being initiated or continued
@@ -16,6 +47,18 @@ type TaskSendParams struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// MetadataBlocking is the TaskSendParams metadata key that, set to false,
+// asks message/send to return as soon as the task is accepted instead of
+// waiting for the agent to finish it
+const MetadataBlocking = "blocking"
+
+// Blocking reports whether message/send should wait for the agent to finish
+// the task before responding. Sends block unless MetadataBlocking is false.
+func (p TaskSendParams) Blocking() bool {
+	blocking, ok := p.Metadata[MetadataBlocking].(bool)
+	return !ok || blocking
+}
+
 // TaskIDParams represents the base parameters for task ID-based operations
 type TaskIDParams struct {
 	// ID is the unique identifier of the task
@@ -31,6 +74,18 @@ type TaskQueryParams struct {
 	HistoryLength *int `json:"historyLength,omitempty"`
 }
 
+// ListTasksParams represents the parameters for listing tasks
+type ListTasksParams struct {
+	// State optionally restricts the result to tasks in this state
+	State TaskState `json:"state,omitempty"`
+	// SessionID optionally restricts the result to tasks in this session
+	SessionID string `json:"sessionId,omitempty"`
+	// Limit is the maximum number of tasks to return; the server picks a default when zero
+	Limit int `json:"limit,omitempty"`
+	// Cursor is the opaque NextCursor of a previous page, empty for the first page
+	Cursor string `json:"cursor,omitempty"`
+}
+
 // PushNotificationConfig represents the configuration for push notifications
 type PushNotificationConfig struct {
 	// URL is the endpoint where the agent should send notifications
@@ -63,6 +118,13 @@ type GetTaskRequest struct {
 	Params TaskQueryParams `json:"params"`
 }
 
+// ListTasksRequest represents a request to list tasks
+type ListTasksRequest struct {
+	JSONRPCRequest
+	Method string          `json:"method"`
+	Params ListTasksParams `json:"params"`
+}
+
 // CancelTaskRequest represents a request to cancel a task
 type CancelTaskRequest struct {
 	JSONRPCRequest
@@ -70,6 +132,13 @@ type CancelTaskRequest struct {
 	Params TaskIDParams `json:"params"`
 }
 
+// DeleteTaskRequest represents a request to delete a task
+type DeleteTaskRequest struct {
+	JSONRPCRequest
+	Method string       `json:"method"`
+	Params TaskIDParams `json:"params"`
+}
+
 // SetTaskPushNotificationRequest represents a request to set task notifications
 type SetTaskPushNotificationRequest struct {
 	JSONRPCRequest
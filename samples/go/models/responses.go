@@ -4,17 +4,52 @@ package models
 type ErrorCode int
 
 const (
-	ErrorCodeParseError                   ErrorCode = -32700
-	ErrorCodeInvalidRequest               ErrorCode = -32600
-	ErrorCodeMethodNotFound               ErrorCode = -32601
-	ErrorCodeInvalidParams                ErrorCode = -32602
-	ErrorCodeInternalError                ErrorCode = -32603
-	ErrorCodeTaskNotFound                 ErrorCode = -32000
-	ErrorCodeTaskNotCancelable            ErrorCode = -32001
-	ErrorCodePushNotificationNotSupported ErrorCode = -32002
-	ErrorCodeUnsupportedOperation         ErrorCode = -32003
+	// Standard JSON-RPC 2.0 errors
+	ErrorCodeParseError     ErrorCode = -32700
+	ErrorCodeInvalidRequest ErrorCode = -32600
+	ErrorCodeMethodNotFound ErrorCode = -32601
+	ErrorCodeInvalidParams  ErrorCode = -32602
+	ErrorCodeInternalError  ErrorCode = -32603
+
+	// A2A-specific errors
+	ErrorCodeTaskNotFound                 ErrorCode = -32001
+	ErrorCodeTaskNotCancelable            ErrorCode = -32002
+	ErrorCodePushNotificationNotSupported ErrorCode = -32003
+	ErrorCodeUnsupportedOperation         ErrorCode = -32004
+	ErrorCodeContentTypeNotSupported      ErrorCode = -32005
+	ErrorCodeInvalidAgentResponse         ErrorCode = -32006
 )
 
+// Message returns the canonical message for the error code
+func (e ErrorCode) Message() string {
+	switch e {
+	case ErrorCodeParseError:
+		return "Invalid JSON payload"
+	case ErrorCodeInvalidRequest:
+		return "Request payload validation error"
+	case ErrorCodeMethodNotFound:
+		return "Method not found"
+	case ErrorCodeInvalidParams:
+		return "Invalid parameters"
+	case ErrorCodeInternalError:
+		return "Internal error"
+	case ErrorCodeTaskNotFound:
+		return "Task not found"
+	case ErrorCodeTaskNotCancelable:
+		return "Task cannot be canceled"
+	case ErrorCodePushNotificationNotSupported:
+		return "Push Notification is not supported"
+	case ErrorCodeUnsupportedOperation:
+		return "This operation is not supported"
+	case ErrorCodeContentTypeNotSupported:
+		return "Incompatible content types"
+	case ErrorCodeInvalidAgentResponse:
+		return "Invalid agent response"
+	default:
+		return "Unknown error"
+	}
+}
+
 // A2AError represents an error in the A2A protocol
 type A2AError struct {
 	JSONRPCError
@@ -42,6 +77,13 @@ type GetTaskResponse struct {
 	Error  *A2AError `json:"error,omitempty"`
 }
 
+// ListTasksResponse represents a response to a list tasks request
+type ListTasksResponse struct {
+	JSONRPCResponse
+	Result *TaskList `json:"result,omitempty"`
+	Error  *A2AError `json:"error,omitempty"`
+}
+
 // CancelTaskResponse represents a response to a cancel task request
 type CancelTaskResponse struct {
 	JSONRPCResponse
@@ -49,6 +91,14 @@ type CancelTaskResponse struct {
 	Error  *A2AError `json:"error,omitempty"`
 }
 
+// DeleteTaskResponse represents a response to a delete task request. The
+// result echoes the ID of the deleted task.
+type DeleteTaskResponse struct {
+	JSONRPCResponse
+	Result *TaskIDParams `json:"result,omitempty"`
+	Error  *A2AError     `json:"error,omitempty"`
+}
+
 // GetTaskHistoryResponse represents a response to a get task history request
 type GetTaskHistoryResponse struct {
 	JSONRPCResponse
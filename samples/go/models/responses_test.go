@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestErrorCodeMessage(t *testing.T) {
+	tests := []struct {
+		code ErrorCode
+		want string
+	}{
+		{ErrorCodeParseError, "Invalid JSON payload"},
+		{ErrorCodeInvalidParams, "Invalid parameters"},
+		{ErrorCodeTaskNotFound, "Task not found"},
+		{ErrorCodeTaskNotCancelable, "Task cannot be canceled"},
+		{ErrorCodeContentTypeNotSupported, "Incompatible content types"},
+		{ErrorCode(-1), "Unknown error"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.Message(); got != tt.want {
+			t.Errorf("ErrorCode(%d).Message() = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
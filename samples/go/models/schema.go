@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaDialect is the JSON Schema draft GenerateSchema targets
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	fileContentType = reflect.TypeOf((*FileContent)(nil)).Elem()
+)
+
+// GenerateSchema returns a JSON Schema document describing the agent card,
+// message/send parameters and the JSON-RPC envelopes, derived from the Go
+// types and their json tags so that other languages can generate clients.
+// Each type is a definition under $defs; fields without omitempty are
+// required.
+func GenerateSchema() ([]byte, error) {
+	g := &schemaGenerator{defs: make(map[string]any)}
+	for _, v := range []any{AgentCard{}, TaskSendParams{}, JSONRPCRequest{}, JSONRPCResponse{}, JSONRPCError{}} {
+		g.schemaFor(reflect.TypeOf(v))
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": schemaDialect,
+		"$defs":   g.defs,
+	}, "", "  ")
+}
+
+// schemaGenerator collects a definition for each named struct it visits
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+// schemaFor returns the schema for t, referring to named structs by $ref
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == fileContentType:
+		return map[string]any{"anyOf": []any{
+			g.schemaFor(reflect.TypeOf(FileContentBytes{})),
+			g.schemaFor(reflect.TypeOf(FileContentURI{})),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, seen := g.defs[t.Name()]; !seen {
+			// Reserve the name first so that recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return ref
+	default:
+		// Interfaces such as JSON-RPC params and results accept any value
+		return map[string]any{}
+	}
+}
+
+// structSchema describes the JSON object a struct encodes to
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	g.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the properties of t's exported fields, flattening embedded
+// structs the way encoding/json does
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.schemaFor(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
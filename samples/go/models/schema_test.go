@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	data, err := GenerateSchema()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	type definition struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	var schema struct {
+		Defs map[string]definition `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to unmarshal schema: %v", err)
+	}
+
+	card, ok := schema.Defs["AgentCard"]
+	if !ok {
+		t.Fatal("Expected an AgentCard definition")
+	}
+	for _, name := range []string{"name", "url", "version", "skills"} {
+		if _, ok := card.Properties[name]; !ok {
+			t.Errorf("Expected AgentCard property %q", name)
+		}
+		if !slices.Contains(card.Required, name) {
+			t.Errorf("Expected AgentCard to require %q, got %v", name, card.Required)
+		}
+	}
+	if slices.Contains(card.Required, "description") {
+		t.Error("Expected optional description not to be required")
+	}
+
+	// Nested and embedded types are described too
+	for _, name := range []string{"AgentSkill", "TaskSendParams", "Message", "Part", "JSONRPCRequest", "JSONRPCResponse", "JSONRPCError"} {
+		if _, ok := schema.Defs[name]; !ok {
+			t.Errorf("Expected a %s definition", name)
+		}
+	}
+	if _, ok := schema.Defs["JSONRPCRequest"].Properties["jsonrpc"]; !ok {
+		t.Error("Expected embedded JSONRPCMessage fields to be flattened into JSONRPCRequest")
+	}
+}
@@ -1,5 +1,13 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // FileContentBase represents the base structure for file content
 type FileContentBase struct {
 	// Name is the optional name of the file
@@ -30,10 +38,19 @@ type FileContent interface {
 func (FileContentBytes) IsFileContent() {}
 func (FileContentURI) IsFileContent()   {}
 
+// PartType discriminates the payload carried by a Part
+type PartType string
+
+const (
+	PartTypeText PartType = "text"
+	PartTypeFile PartType = "file"
+	PartTypeData PartType = "data"
+)
+
 // Part represents a part of a message or artifact
 type Part struct {
-	// Type identifies the type of this part
-	Type *string `json:"type,omitempty"`
+	// Type identifies the type of this part. Inferred from the payload when empty.
+	Type PartType `json:"type,omitempty"`
 	// Text is the text content for text parts
 	Text *string `json:"text,omitempty"`
 	// File is the file content for file parts
@@ -44,6 +61,97 @@ type Part struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// UnmarshalJSON decodes a Part, resolving the file content to
+// FileContentBytes or FileContentURI based on which key is present
+func (p *Part) UnmarshalJSON(data []byte) error {
+	type partAlias Part
+	aux := struct {
+		*partAlias
+		File json.RawMessage `json:"file,omitempty"`
+	}{
+		partAlias: (*partAlias)(p),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.File = nil
+	if len(aux.File) == 0 || string(aux.File) == "null" {
+		return p.normalize()
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(aux.File, &keys); err != nil {
+		return err
+	}
+
+	switch {
+	case keys["bytes"] != nil:
+		var file FileContentBytes
+		if err := json.Unmarshal(aux.File, &file); err != nil {
+			return err
+		}
+		p.File = file
+	case keys["uri"] != nil:
+		var file FileContentURI
+		if err := json.Unmarshal(aux.File, &file); err != nil {
+			return err
+		}
+		p.File = file
+	}
+	return p.normalize()
+}
+
+// MarshalJSON encodes a Part with its concrete file content
+func (p Part) MarshalJSON() ([]byte, error) {
+	if err := p.normalize(); err != nil {
+		return nil, err
+	}
+	type partAlias Part
+	return json.Marshal(partAlias(p))
+}
+
+// normalize checks that exactly one payload is set and that it matches
+// Type, inferring Type from the payload when it is empty
+func (p *Part) normalize() error {
+	var set []PartType
+	if p.Text != nil {
+		set = append(set, PartTypeText)
+	}
+	if p.File != nil {
+		set = append(set, PartTypeFile)
+	}
+	if p.Data != nil {
+		set = append(set, PartTypeData)
+	}
+
+	switch {
+	case len(set) == 0 && p.Type != "":
+		return fmt.Errorf("part of type %q has no %s content", p.Type, p.Type)
+	case len(set) == 0:
+		return fmt.Errorf("part has no text, file, or data content")
+	case len(set) > 1:
+		return fmt.Errorf("part has multiple payloads set: %v", set)
+	}
+
+	switch p.Type {
+	case "":
+		p.Type = set[0]
+	case PartTypeText, PartTypeFile, PartTypeData:
+		if p.Type != set[0] {
+			return fmt.Errorf("part of type %q has no %s content", p.Type, p.Type)
+		}
+	default:
+		return fmt.Errorf("unknown part type %q", p.Type)
+	}
+	return nil
+}
+
+// Validate checks that the part carries exactly one payload matching its type
+func (p Part) Validate() error {
+	return p.normalize()
+}
+
 // Artifact represents an output or intermediate file from a task
 type Artifact struct {
 	// Name is an optional name for the artifact
@@ -62,21 +170,155 @@ type Artifact struct {
 	LastChunk *bool `json:"lastChunk,omitempty"`
 }
 
+// Validate checks that every part of the artifact is valid, so that the
+// artifact can be encoded
+func (a Artifact) Validate() error {
+	for i, part := range a.Parts {
+		if err := part.Validate(); err != nil {
+			return fmt.Errorf("part %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
 // TaskStatus represents the status of a task
 type TaskStatus struct {
 	State TaskState `json:"state"`
+	// Message is an optional agent message explaining the status, e.g. what input is required
+	Message *Message `json:"message,omitempty"`
+	// Timestamp is when the task entered this state
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
 // Task represents an A2A task
 type Task struct {
-	ID     string     `json:"id"`
-	Status TaskStatus `json:"status"`
+	ID string `json:"id"`
+	// SessionID groups related tasks, e.g. the turns of a multi-agent conversation
+	SessionID *string    `json:"sessionId,omitempty"`
+	Status    TaskStatus `json:"status"`
+	// Artifacts are the outputs generated by the task
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// History is the task's message history, populated on request. It is
+	// omitted when nil but encoded as [] when empty, e.g. for historyLength 0.
+	History []Message `json:"history,omitzero"`
+	// StatusHistory is the task's state transitions in chronological order,
+	// populated when the agent supports state transition history
+	StatusHistory []TaskStatus `json:"statusHistory,omitempty"`
+	// Metadata collects the metadata sent with the task's requests, later
+	// requests overriding earlier keys
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// Validate checks that the task has an ID and a known state, that its status
+// message and artifacts can be encoded, and that its status history, when
+// present, ends in its current state without leaving a terminal state
+func (t Task) Validate() error {
+	var errs []error
+	if t.ID == "" {
+		errs = append(errs, errors.New("task has no ID"))
+	}
+	switch t.Status.State {
+	case TaskStateSubmitted, TaskStateWorking, TaskStateInputRequired,
+		TaskStateCompleted, TaskStateCanceled, TaskStateFailed, TaskStateUnknown:
+	default:
+		errs = append(errs, fmt.Errorf("task has invalid state %q", t.Status.State))
+	}
+	if t.Status.Message != nil {
+		if err := t.Status.Message.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("status message: %w", err))
+		}
+	}
+	for i, artifact := range t.Artifacts {
+		if err := artifact.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("artifact %d: %w", i, err))
+		}
+	}
+	for i, status := range t.StatusHistory {
+		if status.State.IsTerminal() && i < len(t.StatusHistory)-1 {
+			errs = append(errs, fmt.Errorf("status history continues after terminal state %s", status.State))
+			break
+		}
+	}
+	if n := len(t.StatusHistory); n > 0 && t.StatusHistory[n-1].State != t.Status.State {
+		errs = append(errs, fmt.Errorf("status history ends in %s but task is %s", t.StatusHistory[n-1].State, t.Status.State))
+	}
+	return errors.Join(errs...)
+}
+
+// InputPrompt returns the agent's explanation of what input it needs when
+// the task is waiting for input. ok is false in any other state.
+func (t Task) InputPrompt() (prompt *Message, ok bool) {
+	if t.Status.State != TaskStateInputRequired {
+		return nil, false
+	}
+	return t.Status.Message, true
+}
+
+// Roles a message may be sent with
+const (
+	// RoleUser marks messages sent by the client on behalf of a user
+	RoleUser = "user"
+	// RoleAgent marks messages sent by the agent
+	RoleAgent = "agent"
+)
+
 // Message represents a message in the A2A protocol
 type Message struct {
 	Role  string `json:"role"`
 	Parts []Part `json:"parts"`
+	// Timestamp is when the server received the message. Servers set it,
+	// so that task history can be ordered across restarts and stores.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// Text returns the text parts of the message joined by newlines
+func (m Message) Text() string {
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Text != nil {
+			texts = append(texts, *part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Validate checks that the message comes from the user or the agent and
+// has at least one well-formed part
+func (m Message) Validate() error {
+	switch m.Role {
+	case "":
+		return errors.New("message has no role")
+	case RoleUser, RoleAgent:
+	default:
+		return fmt.Errorf("unknown message role %q", m.Role)
+	}
+	if len(m.Parts) == 0 {
+		return errors.New("message has no parts")
+	}
+	for i, part := range m.Parts {
+		if err := part.Validate(); err != nil {
+			return fmt.Errorf("part %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// TaskList represents one page of tasks returned by tasks/list
+type TaskList struct {
+	// Tasks is the page of tasks, most recently created first
+	Tasks []Task `json:"tasks"`
+	// NextCursor fetches the following page; it is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// TaskNotModified is the tasks/get result when the task still matches an
+// ETag the caller sent in an If-None-Match header, sparing a poller from
+// downloading identical state again
+type TaskNotModified struct {
+	// ID is the ID of the unchanged task
+	ID string `json:"id"`
+	// NotModified is always true, telling this result apart from a Task
+	NotModified bool `json:"notModified"`
 }
 
 // TaskHistory represents the history of a task
@@ -108,3 +350,38 @@ type TaskArtifactUpdateEvent struct {
 	// Metadata is optional metadata associated with this update event
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
+
+// StreamEvent is an event on a task's stream: a *TaskStatusUpdateEvent or a
+// *TaskArtifactUpdateEvent
+type StreamEvent interface {
+	IsStreamEvent()
+}
+
+func (*TaskStatusUpdateEvent) IsStreamEvent()   {}
+func (*TaskArtifactUpdateEvent) IsStreamEvent() {}
+
+// DecodeStreamEvent decodes a streaming result into a *TaskArtifactUpdateEvent
+// if it carries an artifact, or a *TaskStatusUpdateEvent if it carries a status
+func DecodeStreamEvent(data []byte) (StreamEvent, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case keys["artifact"] != nil:
+		var event TaskArtifactUpdateEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	case keys["status"] != nil:
+		var event TaskStatusUpdateEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	default:
+		return nil, errors.New("stream event has neither a status nor an artifact")
+	}
+}
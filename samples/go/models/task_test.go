@@ -0,0 +1,206 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPartFileContentJSON(t *testing.T) {
+	name := "report.pdf"
+
+	tests := []struct {
+		name string
+		json string
+		want FileContent
+	}{
+		{
+			name: "bytes",
+			json: `{"type":"file","file":{"name":"report.pdf","bytes":"aGVsbG8="}}`,
+			want: FileContentBytes{FileContentBase: FileContentBase{Name: &name}, Bytes: "aGVsbG8="},
+		},
+		{
+			name: "uri",
+			json: `{"type":"file","file":{"name":"report.pdf","uri":"https://example.com/report.pdf"}}`,
+			want: FileContentURI{FileContentBase: FileContentBase{Name: &name}, URI: "https://example.com/report.pdf"},
+		},
+		{
+			name: "missing file",
+			json: `{"type":"text","text":"hello"}`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var part Part
+			if err := json.Unmarshal([]byte(tt.json), &part); err != nil {
+				t.Fatalf("Failed to unmarshal part: %v", err)
+			}
+			if !reflect.DeepEqual(part.File, tt.want) {
+				t.Errorf("Expected file %#v, got %#v", tt.want, part.File)
+			}
+
+			// Round-trip through MarshalJSON
+			data, err := json.Marshal(part)
+			if err != nil {
+				t.Fatalf("Failed to marshal part: %v", err)
+			}
+			var roundTripped Part
+			if err := json.Unmarshal(data, &roundTripped); err != nil {
+				t.Fatalf("Failed to unmarshal round-tripped part: %v", err)
+			}
+			if !reflect.DeepEqual(roundTripped, part) {
+				t.Errorf("Expected round-tripped part %#v, got %#v", part, roundTripped)
+			}
+		})
+	}
+}
+
+func TestPartTypeValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		wantType PartType
+		wantErr  bool
+	}{
+		{name: "text", json: `{"type":"text","text":"hello"}`, wantType: PartTypeText},
+		{name: "data", json: `{"type":"data","data":{"k":"v"}}`, wantType: PartTypeData},
+		{name: "file", json: `{"type":"file","file":{"uri":"https://example.com/a"}}`, wantType: PartTypeFile},
+		{name: "inferred text", json: `{"text":"hello"}`, wantType: PartTypeText},
+		{name: "inferred data", json: `{"data":{"k":"v"}}`, wantType: PartTypeData},
+		{name: "inferred file", json: `{"file":{"bytes":"aGVsbG8="}}`, wantType: PartTypeFile},
+		{name: "text type without text", json: `{"type":"text","data":{"k":"v"}}`, wantErr: true},
+		{name: "file type without bytes or uri", json: `{"type":"file","file":{"name":"a"}}`, wantErr: true},
+		{name: "multiple payloads", json: `{"text":"hello","data":{"k":"v"}}`, wantErr: true},
+		{name: "no payload", json: `{"type":"text"}`, wantErr: true},
+		{name: "empty", json: `{}`, wantErr: true},
+		{name: "unknown type", json: `{"type":"video","text":"hello"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var part Part
+			err := json.Unmarshal([]byte(tt.json), &part)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got part %#v", part)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if part.Type != tt.wantType {
+				t.Errorf("Expected type %q, got %q", tt.wantType, part.Type)
+			}
+		})
+	}
+}
+
+func TestPartMarshalValidation(t *testing.T) {
+	text := "hello"
+
+	data, err := json.Marshal(Part{Text: &text})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"type":"text","text":"hello"}` {
+		t.Errorf("Expected inferred text type, got %s", data)
+	}
+
+	if _, err := json.Marshal(Part{Type: PartTypeData, Text: &text}); err == nil {
+		t.Error("Expected error for data part carrying text")
+	}
+}
+
+func TestMessageValidate(t *testing.T) {
+	text := "Hello"
+	tests := []struct {
+		name    string
+		message Message
+		wantErr bool
+	}{
+		{"valid", Message{Role: RoleUser, Parts: []Part{{Text: &text}, {Data: map[string]interface{}{"k": "v"}}}}, false},
+		{"agent role", Message{Role: RoleAgent, Parts: []Part{{Text: &text}}}, false},
+		{"no role", Message{Parts: []Part{{Text: &text}}}, true},
+		{"unknown role", Message{Role: "system", Parts: []Part{{Text: &text}}}, true},
+		{"no parts", Message{Role: RoleUser}, true},
+		{"empty part", Message{Role: RoleUser, Parts: []Part{{Text: &text}, {}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.message.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaskValidate(t *testing.T) {
+	status := func(state TaskState) TaskStatus { return TaskStatus{State: state} }
+	tests := []struct {
+		name    string
+		task    Task
+		wantErr bool
+	}{
+		{"valid", Task{ID: "123", Status: status(TaskStateWorking)}, false},
+		{"valid history", Task{ID: "123", Status: status(TaskStateCompleted), StatusHistory: []TaskStatus{status(TaskStateSubmitted), status(TaskStateWorking), status(TaskStateCompleted)}}, false},
+		{"no ID", Task{Status: status(TaskStateWorking)}, true},
+		{"no state", Task{ID: "123"}, true},
+		{"unknown state name", Task{ID: "123", Status: status("done")}, true},
+		{"restarted after terminal", Task{ID: "123", Status: status(TaskStateWorking), StatusHistory: []TaskStatus{status(TaskStateWorking), status(TaskStateCompleted), status(TaskStateWorking)}}, true},
+		{"invalid status message", Task{ID: "123", Status: TaskStatus{State: TaskStateInputRequired, Message: &Message{Role: RoleAgent}}}, true},
+		{"invalid artifact part", Task{ID: "123", Status: status(TaskStateCompleted), Artifacts: []Artifact{{Parts: []Part{{Type: PartTypeText}}}}}, true},
+		{"history behind status", Task{ID: "123", Status: status(TaskStateCompleted), StatusHistory: []TaskStatus{status(TaskStateWorking)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.task.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaskInputPrompt(t *testing.T) {
+	prompt := Message{Role: RoleAgent, Parts: []Part{NewTextPart("Which city?"), NewDataPart(map[string]interface{}{"k": "v"}), NewTextPart("Or a country?")}}
+
+	task := Task{ID: "123", Status: TaskStatus{State: TaskStateInputRequired, Message: &prompt}}
+	got, ok := task.InputPrompt()
+	if !ok || got != &prompt {
+		t.Fatalf("Expected the status message as prompt, got %v, %v", got, ok)
+	}
+	if text := got.Text(); text != "Which city?\nOr a country?" {
+		t.Errorf("Expected text parts joined by newlines, got %q", text)
+	}
+
+	task.Status.State = TaskStateWorking
+	if _, ok := task.InputPrompt(); ok {
+		t.Error("Expected no prompt outside input-required")
+	}
+}
+
+func TestDecodeStreamEvent(t *testing.T) {
+	event, err := DecodeStreamEvent([]byte(`{"id":"t","status":{"state":"working"},"final":false}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status, ok := event.(*TaskStatusUpdateEvent); !ok || status.Status.State != TaskStateWorking {
+		t.Errorf("Expected a working status event, got %#v", event)
+	}
+
+	event, err = DecodeStreamEvent([]byte(`{"id":"t","artifact":{"parts":[{"type":"text","text":"hi"}]}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if artifact, ok := event.(*TaskArtifactUpdateEvent); !ok || artifact.Artifact.Parts[0].Text == nil || *artifact.Artifact.Parts[0].Text != "hi" {
+		t.Errorf("Expected an artifact event, got %#v", event)
+	}
+
+	if _, err := DecodeStreamEvent([]byte(`{"id":"t"}`)); err == nil {
+		t.Error("Expected an error for an event with neither status nor artifact")
+	}
+}
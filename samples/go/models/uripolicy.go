@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrURINotAllowed is returned when a URIPolicy forbids fetching a URI
+var ErrURINotAllowed = errors.New("URI not allowed")
+
+// URIPolicy restricts which file URIs may be fetched, guarding agents that
+// download FileContentURI parts against server-side request forgery. The
+// zero value allows any public http or https URI and blocks loopback,
+// private and link-local addresses such as the cloud metadata endpoint
+// 169.254.169.254.
+type URIPolicy struct {
+	// AllowedHosts, when set, are the only hosts URIs may point at. They may
+	// resolve to private addresses. A "*." prefix matches any subdomain.
+	AllowedHosts []string
+	// DeniedHosts are hosts URIs may never point at, with the same syntax
+	DeniedHosts []string
+	// AllowPrivateNetworks permits loopback, private and link-local addresses
+	AllowPrivateNetworks bool
+}
+
+// Check reports whether uri may be fetched, returning an error wrapping
+// ErrURINotAllowed if not. Host names are checked as written; addresses they
+// resolve to are checked when FileBytesContext connects.
+func (p URIPolicy) Check(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrURINotAllowed, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrURINotAllowed, u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case matchHost(p.DeniedHosts, host):
+		return fmt.Errorf("%w: host %s is denied", ErrURINotAllowed, host)
+	case len(p.AllowedHosts) > 0 && !matchHost(p.AllowedHosts, host):
+		return fmt.Errorf("%w: host %s is not allowed", ErrURINotAllowed, host)
+	case p.checksAddresses(host) && isPrivateHost(host):
+		return fmt.Errorf("%w: host %s is a private address", ErrURINotAllowed, host)
+	}
+	return nil
+}
+
+// checksAddresses reports whether connections to host must be refused when
+// they reach a private address
+func (p URIPolicy) checksAddresses(host string) bool {
+	return !p.AllowPrivateNetworks && !matchHost(p.AllowedHosts, host)
+}
+
+// httpClient returns a client that fetches URIs under the policy.
+// Connections go direct rather than through a proxy so that the address
+// checked is the one actually dialed, and redirects are checked like the
+// original URI. Each client is used for a single fetch, so connections are
+// not kept alive.
+func (p URIPolicy) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = p.dialContext
+	transport.DisableKeepAlives = true
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.Check(req.URL.String())
+		},
+	}
+}
+
+// dialContext connects to address, refusing private addresses unless the
+// policy allows them for the host being dialed. Deciding per connection
+// covers redirects, whose host may differ from the original URI's.
+func (p URIPolicy) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if p.checksAddresses(strings.ToLower(host)) {
+		dialer.Control = func(_, resolved string, _ syscall.RawConn) error {
+			ip, _, _ := net.SplitHostPort(resolved)
+			if isPrivateHost(ip) {
+				return fmt.Errorf("%w: %s resolves to a private address", ErrURINotAllowed, host)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// matchHost reports whether host matches one of patterns
+func matchHost(patterns []string, host string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			return strings.HasSuffix(host, suffix)
+		}
+		return host == pattern
+	})
+}
+
+// isPrivateHost reports whether host is localhost or an IP address that
+// isn't publicly routable
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsUnspecified()
+}
+
+type uriPolicyKey struct{}
+
+// ContextWithURIPolicy returns a context that makes FileBytesContext fetch
+// URIs under policy. Servers set it on the context passed to task handlers.
+func ContextWithURIPolicy(ctx context.Context, policy URIPolicy) context.Context {
+	return context.WithValue(ctx, uriPolicyKey{}, policy)
+}
+
+// uriPolicyFromContext returns the policy set by ContextWithURIPolicy
+func uriPolicyFromContext(ctx context.Context) (URIPolicy, bool) {
+	policy, ok := ctx.Value(uriPolicyKey{}).(URIPolicy)
+	return policy, ok
+}
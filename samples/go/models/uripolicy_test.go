@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURIPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  URIPolicy
+		uri     string
+		allowed bool
+	}{
+		{name: "public host", uri: "https://example.com/report.pdf", allowed: true},
+		{name: "cloud metadata", uri: "http://169.254.169.254/latest/meta-data/"},
+		{name: "loopback", uri: "http://127.0.0.1:8080/admin"},
+		{name: "IPv6 loopback", uri: "http://[::1]/"},
+		{name: "IPv4-mapped loopback", uri: "http://[::ffff:127.0.0.1]/"},
+		{name: "localhost", uri: "http://localhost/"},
+		{name: "private network", uri: "http://10.0.0.5/file"},
+		{name: "unspecified", uri: "http://0.0.0.0/"},
+		{name: "unsupported scheme", uri: "file:///etc/passwd"},
+		{name: "private networks allowed", policy: URIPolicy{AllowPrivateNetworks: true}, uri: "http://10.0.0.5/file", allowed: true},
+		{name: "allowed host", policy: URIPolicy{AllowedHosts: []string{"files.example.com"}}, uri: "https://files.example.com/a", allowed: true},
+		{name: "host not allowed", policy: URIPolicy{AllowedHosts: []string{"files.example.com"}}, uri: "https://example.com/a"},
+		{name: "allowed private host", policy: URIPolicy{AllowedHosts: []string{"10.0.0.5"}}, uri: "http://10.0.0.5/file", allowed: true},
+		{name: "allowed subdomain", policy: URIPolicy{AllowedHosts: []string{"*.example.com"}}, uri: "https://cdn.example.com/a", allowed: true},
+		{name: "denied host", policy: URIPolicy{DeniedHosts: []string{"*.example.com"}}, uri: "https://cdn.example.com/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.uri)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected %s to be allowed, got %v", tt.uri, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrURINotAllowed) {
+				t.Errorf("Expected %s to be blocked, got %v", tt.uri, err)
+			}
+		})
+	}
+}
+
+func TestPartFileBytesURIPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+	}))
+	defer ts.Close()
+
+	part := Part{File: FileContentURI{URI: ts.URL + "/file"}}
+
+	// The test server listens on loopback, which the default policy refuses
+	ctx := ContextWithURIPolicy(context.Background(), URIPolicy{})
+	if _, _, err := part.FileBytesContext(ctx); !errors.Is(err, ErrURINotAllowed) {
+		t.Errorf("Expected ErrURINotAllowed, got %v", err)
+	}
+
+	ctx = ContextWithURIPolicy(context.Background(), URIPolicy{AllowedHosts: []string{"127.0.0.1"}})
+	data, _, err := part.FileBytesContext(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "data" {
+		t.Errorf("Expected data, got %q", data)
+	}
+}
+
+func TestURIPolicyDialChecksEachHost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	address := ts.Listener.Addr().String()
+
+	// Allowing another host must not let connections to this one through,
+	// as when an allowed host redirects to a name resolving to loopback
+	policy := URIPolicy{AllowedHosts: []string{"files.example.com"}}
+	if _, err := policy.dialContext(context.Background(), "tcp", address); !errors.Is(err, ErrURINotAllowed) {
+		t.Errorf("Expected ErrURINotAllowed dialing %s, got %v", address, err)
+	}
+
+	policy = URIPolicy{AllowedHosts: []string{"127.0.0.1"}}
+	conn, err := policy.dialContext(context.Background(), "tcp", address)
+	if err != nil {
+		t.Fatalf("Expected allowed host to connect, got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithAccessLog logs every JSON-RPC request the server dispatches, once it
+// has been answered, to the logger set with WithLogger. Entries record the
+// method, task ID, duration and, since A2A errors travel in a 200 response,
+// the JSON-RPC error code of failed requests.
+func WithAccessLog() Option {
+	return func(s *A2AServer) {
+		s.accessLog = true
+	}
+}
+
+// logAccess writes the access log entry for a request that started at start
+func (s *A2AServer) logAccess(r *http.Request, method, taskID string, start time.Time, rec *resultRecorder) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("task_id", taskID),
+		slog.String("request_id", RequestIDFromContext(r.Context())),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if rec.failed {
+		attrs = append(attrs, slog.Int("error_code", int(rec.code)))
+	}
+	s.logger.LogAttrs(r.Context(), slog.LevelInfo, "request handled", attrs...)
+}
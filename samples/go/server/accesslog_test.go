@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithLogger(logger), WithAccessLog())
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "missing"},
+	})
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		if entry["msg"] == "request handled" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 access log entries, got %s", buf.String())
+	}
+
+	sent, failed := entries[0], entries[1]
+	if sent["method"] != models.MethodMessageSend || sent["task_id"] != "test-task" {
+		t.Errorf("Expected method and task ID for the send, got %v", sent)
+	}
+	if _, ok := sent["error_code"]; ok {
+		t.Errorf("Expected no error code for a successful request, got %v", sent)
+	}
+	if _, ok := sent["duration"]; !ok {
+		t.Errorf("Expected a duration, got %v", sent)
+	}
+
+	if failed["method"] != models.MethodTasksGet || failed["task_id"] != "missing" {
+		t.Errorf("Expected method and task ID for the failed get, got %v", failed)
+	}
+	if code, _ := failed["error_code"].(float64); int(code) != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected error code %d, got %v", models.ErrorCodeTaskNotFound, failed["error_code"])
+	}
+}
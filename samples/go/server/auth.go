@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"a2a/models"
+)
+
+// RequireAuth returns middleware that enforces the authentication schemes
+// declared in the agent card. Each request must carry an
+// "Authorization: <scheme> <credential>" header naming one of the card's
+// schemes; verify decides whether the credential is valid. Requests failing
+// the check get a JSON-RPC error and never reach a handler. The agent card
+// itself and CORS preflight requests stay public so clients can discover how
+// to authenticate, as does the health check.
+func RequireAuth(card models.AgentCard, verify func(scheme, credential string) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if card.Authentication == nil || len(card.Authentication.Schemes) == 0 {
+			return next
+		}
+		schemes := card.Authentication.Schemes
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == agentCardPath || r.URL.Path == healthPath || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				writeAuthError(w, models.ErrorCodeInvalidRequest, "Missing credentials")
+				return
+			}
+
+			given, credential, _ := strings.Cut(header, " ")
+			scheme := ""
+			for _, declared := range schemes {
+				if strings.EqualFold(declared, given) {
+					scheme = declared
+					break
+				}
+			}
+			if scheme == "" {
+				writeAuthError(w, models.ErrorCodeUnsupportedOperation, "Unsupported authentication scheme")
+				return
+			}
+
+			if err := verify(scheme, strings.TrimSpace(credential)); err != nil {
+				writeAuthError(w, models.ErrorCodeInvalidRequest, "Invalid credentials")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeAuthError rejects a request before it has been decoded, so the
+// response carries no ID
+func writeAuthError(w http.ResponseWriter, code models.ErrorCode, message string) {
+	response := models.JSONRPCResponse{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Error: &models.JSONRPCError{
+			Code:    int(code),
+			Message: message,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
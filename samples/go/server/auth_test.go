@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"a2a/models"
+)
+
+// doAuthRequest sends a tasks/get request through RequireAuth with the given
+// Authorization header, returning the decoded response
+func doAuthRequest(t *testing.T, authorization string) models.JSONRPCResponse {
+	t.Helper()
+
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{"Bearer"}}
+	server := NewA2AServer(card, mockTaskHandler)
+	server.Use(RequireAuth(card, func(scheme, credential string) error {
+		if scheme != "Bearer" || credential != "secret" {
+			return errors.New("bad token")
+		}
+		return nil
+	}))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodTasksGet,
+		Params:         models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+
+	server.newHTTPServer().Handler.ServeHTTP(w, req)
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestRequireAuthValidToken(t *testing.T) {
+	response := doAuthRequest(t, "bearer secret")
+
+	// The request reaches the handler, which reports the missing task
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected task not found error, got %+v", response.Error)
+	}
+}
+
+func TestRequireAuthMissingHeader(t *testing.T) {
+	response := doAuthRequest(t, "")
+
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) {
+		t.Errorf("Expected invalid request error, got %+v", response.Error)
+	}
+}
+
+func TestRequireAuthInvalidToken(t *testing.T) {
+	response := doAuthRequest(t, "Bearer wrong")
+
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) {
+		t.Errorf("Expected invalid request error, got %+v", response.Error)
+	}
+}
+
+func TestRequireAuthUnsupportedScheme(t *testing.T) {
+	response := doAuthRequest(t, "Basic dXNlcjpwYXNz")
+
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeUnsupportedOperation) {
+		t.Errorf("Expected unsupported operation error, got %+v", response.Error)
+	}
+}
+
+func TestRequireAuthAgentCardPublic(t *testing.T) {
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{"Bearer"}}
+	server := NewA2AServer(card, mockTaskHandler)
+	server.Use(RequireAuth(card, func(scheme, credential string) error {
+		return errors.New("no credentials accepted")
+	}))
+
+	for _, path := range []string{agentCardPath, healthPath} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.newHTTPServer().Handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusOK, path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithCompression gzip- or deflate-encodes responses for clients that
+// accept it. Streams are left uncompressed so each event still reaches the
+// client as soon as it is flushed.
+func WithCompression() Option {
+	return func(s *A2AServer) {
+		s.compression = true
+	}
+}
+
+// compress wraps next so that its responses are compressed according to the
+// request's Accept-Encoding header
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// WebSocket upgrades need the raw connection
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		if f, ok := w.(http.Flusher); ok {
+			next.ServeHTTP(flushingCompressWriter{cw, f}, r)
+			return
+		}
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip, or returns "" if neither is acceptable
+func negotiateEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, entry := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter compresses the response body unless the handler streams
+// events, already encoded the body, or sends no body at all. The decision is
+// made when the header is written.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	passthrough := strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") ||
+		h.Get("Content-Encoding") != "" ||
+		code == http.StatusNoContent || code == http.StatusNotModified
+	if !passthrough {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.encoder = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.encoder, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.encoder.Write(p)
+}
+
+// Close finishes the compressed body
+func (w *compressWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushingCompressWriter is a compressWriter over a writer that can flush
+type flushingCompressWriter struct {
+	*compressWriter
+	http.Flusher
+}
+
+// Flush writes out any compressed data buffered so far
+func (w flushingCompressWriter) Flush() {
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.Flusher.Flush()
+}
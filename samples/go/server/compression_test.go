@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func compressionRequest(t *testing.T, method, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCompression())
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: method,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+
+	server.newHTTPServer().Handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressionGzip(t *testing.T) {
+	w := compressionRequest(t, models.MethodMessageSend, "gzip, deflate")
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, got %q", got)
+	}
+
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(reader).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.ID != "test-task" {
+		t.Errorf("Expected task ID test-task, got %s", task.ID)
+	}
+}
+
+func TestCompressionNotAccepted(t *testing.T) {
+	w := compressionRequest(t, models.MethodMessageSend, "gzip;q=0")
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected no Content-Encoding, got %q", got)
+	}
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode plain response: %v", err)
+	}
+}
+
+func TestCompressionSkipsStreams(t *testing.T) {
+	w := compressionRequest(t, models.MethodMessageStream, "gzip")
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected streams to be uncompressed, got Content-Encoding %q", got)
+	}
+	if !strings.HasPrefix(w.Body.String(), "id: ") {
+		t.Errorf("Expected plain SSE frames, got %q", w.Body.String())
+	}
+}
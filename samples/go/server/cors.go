@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+
+	"a2a/models"
+)
+
+// WithCORS allows browser clients served from the given origins to call the
+// agent. Use "*" to allow any origin. Preflight OPTIONS requests are answered
+// directly. Without this option, only streaming responses carry a permissive
+// Access-Control-Allow-Origin header.
+func WithCORS(allowedOrigins []string) Option {
+	return func(s *A2AServer) {
+		s.corsOrigins = allowedOrigins
+	}
+}
+
+// handleCORS adds CORS headers when the request's origin is allowed and
+// answers preflight requests, reporting true when the request has been fully
+// handled
+func (s *A2AServer) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	if s.corsOrigins == nil {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	allowed := origin != "" && (slices.Contains(s.corsOrigins, "*") || slices.Contains(s.corsOrigins, origin))
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if allowed {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", models.RequestIDHeader+", ETag")
+	}
+
+	if r.Method != http.MethodOptions {
+		return false
+	}
+	if !allowed {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+	h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, If-None-Match, "+models.RequestIDHeader)
+	h.Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
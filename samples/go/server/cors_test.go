@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCORS([]string{"https://ui.example.com"}))
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://ui.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://ui.example.com" {
+		t.Errorf("Expected allowed origin https://ui.example.com, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got == "" {
+		t.Error("Expected Access-Control-Allow-Methods to be set")
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCORS([]string{"https://ui.example.com"}))
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no allowed origin, got %q", got)
+	}
+
+	// Streams must not fall back to allowing every origin either
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		},
+	})
+	req = httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no allowed origin on stream, got %q", got)
+	}
+}
+
+func TestCORSJSONRPC(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCORS([]string{"*"}))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodTasksGet,
+		Params:         models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Origin", "https://ui.example.com")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://ui.example.com" {
+		t.Errorf("Expected allowed origin https://ui.example.com, got %q", got)
+	}
+}
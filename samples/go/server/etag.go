@@ -0,0 +1,34 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"a2a/models"
+)
+
+// taskETag returns a strong ETag for the task as it is about to be returned.
+// It is derived from the task's full content, so a change in status,
+// artifacts or history produces a new ETag.
+func taskETag(task *models.Task) (string, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// The header may list several ETags, weak ones included, or be "*".
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"a2a/models"
+)
+
+// getTaskIfNoneMatch sends tasks/get with an If-None-Match header, returning
+// the response and its ETag
+func getTaskIfNoneMatch(t *testing.T, server *A2AServer, taskID, ifNoneMatch string) (models.JSONRPCResponse, string) {
+	t.Helper()
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: models.MethodTasksGet,
+		Params: models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: taskID}},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response, w.Header().Get("ETag")
+}
+
+func TestTaskGetETag(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockInputRequiredTaskHandler)
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	}
+	doJSONRPC(t, server, models.MethodMessageSend, params)
+
+	first, etag := getTaskIfNoneMatch(t, server, "test-task", "")
+	if first.Error != nil {
+		t.Fatalf("Expected no error, got %v", first.Error)
+	}
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	// Polling again with the ETag reports that nothing changed
+	second, secondETag := getTaskIfNoneMatch(t, server, "test-task", etag)
+	if second.Error != nil {
+		t.Fatalf("Expected no error, got %v", second.Error)
+	}
+	var notModified models.TaskNotModified
+	decodeResult(t, second, &notModified)
+	if !notModified.NotModified || notModified.ID != "test-task" {
+		t.Errorf("Expected a not modified result, got %+v", second.Result)
+	}
+	if secondETag != etag {
+		t.Errorf("Expected ETag %s, got %s", etag, secondETag)
+	}
+
+	// Once the task changes, the full task comes back under a new ETag
+	doJSONRPC(t, server, models.MethodMessageSend, params)
+	third, thirdETag := getTaskIfNoneMatch(t, server, "test-task", etag)
+	var task models.Task
+	decodeResult(t, third, &task)
+	if task.ID != "test-task" || task.Status.State != models.TaskStateInputRequired {
+		t.Errorf("Expected the updated task, got %+v", third.Result)
+	}
+	if thirdETag == etag {
+		t.Error("Expected the ETag to change with the task")
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"abc"`, want: true},
+		{header: `W/"abc"`, want: true},
+		{header: `"xyz", "abc"`, want: true},
+		{header: "*", want: true},
+		{header: `"xyz"`},
+		{header: ""},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
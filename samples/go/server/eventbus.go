@@ -0,0 +1,103 @@
+package server
+
+import "sync"
+
+// eventBus fans out the events of one run of a streaming task to every
+// connection watching it: the stream that started the task and any
+// tasks/resubscribe connections. Events are numbered and kept in the task's
+// replay buffer as they are published. Only the goroutine running the task
+// publishes to and closes its bus.
+type eventBus struct {
+	mu     sync.Mutex
+	subs   []*subscriber
+	closed bool
+	replay *replayBuffer
+}
+
+// subscriber receives a bus's events on behalf of one connection until done
+// is closed
+type subscriber struct {
+	events chan streamEvent
+	done   <-chan struct{}
+}
+
+// subscribe adds a subscriber whose channel buffers up to buffer events. It
+// returns nil if the bus has already been closed.
+func (b *eventBus) subscribe(buffer int, done <-chan struct{}) *subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	sub := &subscriber{events: make(chan streamEvent, buffer), done: done}
+	b.subs = append(b.subs, sub)
+	return sub
+}
+
+// resume subscribes like subscribe for a client that has seen the events up
+// to lastID, returning the kept events it missed. Every event published is
+// either among them or delivered to the subscriber, never both.
+func (b *eventBus) resume(lastID uint64, buffer int, done <-chan struct{}) (*subscriber, []streamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	missed := b.replay.since(lastID)
+	if b.closed {
+		return nil, missed
+	}
+	sub := &subscriber{events: make(chan streamEvent, buffer), done: done}
+	b.subs = append(b.subs, sub)
+	return sub, missed
+}
+
+// unsubscribe removes a subscriber whose connection has gone away
+func (b *eventBus) unsubscribe(target *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, sub := range b.subs {
+		if sub == target {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish numbers an event and delivers it to every subscriber, waiting for
+// each until it has room or its connection is gone
+func (b *eventBus) publish(event any) {
+	b.mu.Lock()
+	e := b.replay.add(event)
+	subs := append([]*subscriber(nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.events <- e:
+		case <-sub.done:
+		}
+	}
+}
+
+// close ends every subscription. Closing a closed bus does nothing.
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, sub := range b.subs {
+		close(sub.events)
+	}
+	b.subs = nil
+}
+
+// subscribers returns how many connections are watching the bus
+func (b *eventBus) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestEventBusMultipleSubscribers(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(handlerStarted)
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	streamed := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		streamed <- doStream(t, server, models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		})
+	}()
+	<-handlerStarted
+
+	const watchers = 2
+	resubscribed := make(chan *httptest.ResponseRecorder, watchers)
+	for i := 0; i < watchers; i++ {
+		go func() {
+			resubscribed <- doResubscribe(t, server, "test-task")
+		}()
+	}
+
+	// The original stream and both watchers are attached before the task finishes
+	waitForSubscribers(t, server, "test-task", watchers+1)
+	close(release)
+
+	for i := 0; i < watchers+1; i++ {
+		var w *httptest.ResponseRecorder
+		select {
+		case w = <-streamed:
+		case w = <-resubscribed:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for subscribers to finish")
+		}
+
+		results := streamResults(t, w.Body.String())
+		var final models.TaskStatusUpdateEvent
+		if err := json.Unmarshal(results[len(results)-1], &final); err != nil {
+			t.Fatalf("Failed to unmarshal final event: %v", err)
+		}
+		if final.Status.State != models.TaskStateCompleted || final.Final == nil || !*final.Final {
+			t.Errorf("Expected every subscriber to receive the final completed event, got %s", w.Body.String())
+		}
+	}
+
+	server.mu.RLock()
+	_, running := server.streams["test-task"]
+	server.mu.RUnlock()
+	if running {
+		t.Error("Expected the task's event bus to be removed once it finished")
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"fmt"
+
+	"a2a/models"
+)
+
+// WithFileURIPolicy sets which file URIs messages may reference. Messages
+// with file parts pointing elsewhere are rejected with InvalidParams, and
+// task handlers fetching file parts with Part.FileBytesContext are held to
+// the same policy. By default URIs resolving to loopback, private or
+// link-local addresses are refused.
+func WithFileURIPolicy(policy models.URIPolicy) Option {
+	return func(s *A2AServer) {
+		s.uriPolicy = policy
+	}
+}
+
+// validateFileURIs checks the file URIs of message against the URI policy,
+// returning the JSON-RPC error code and message to reply with
+func (s *A2AServer) validateFileURIs(message models.Message) (models.ErrorCode, string, bool) {
+	for i, part := range message.Parts {
+		file, ok := part.File.(models.FileContentURI)
+		if !ok {
+			continue
+		}
+		if err := s.uriPolicy.Check(file.URI); err != nil {
+			return models.ErrorCodeInvalidParams, fmt.Sprintf("Invalid file in part %d: %v", i, err), false
+		}
+	}
+	return 0, "", true
+}
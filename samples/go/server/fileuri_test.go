@@ -0,0 +1,61 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func fileURIMessage(uri string) models.TaskSendParams {
+	return models.TaskSendParams{
+		ID: "test-task",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{File: models.FileContentURI{URI: uri}}},
+		},
+	}
+}
+
+func TestFileURIPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		uri     string
+		allowed bool
+	}{
+		{name: "cloud metadata blocked by default", uri: "http://169.254.169.254/latest/meta-data/"},
+		{name: "public host allowed by default", uri: "https://files.example.com/report.pdf", allowed: true},
+		{
+			name:    "allowlisted host",
+			opts:    []Option{WithFileURIPolicy(models.URIPolicy{AllowedHosts: []string{"files.example.com"}})},
+			uri:     "https://files.example.com/report.pdf",
+			allowed: true,
+		},
+		{
+			name: "host outside the allowlist",
+			opts: []Option{WithFileURIPolicy(models.URIPolicy{AllowedHosts: []string{"files.example.com"}})},
+			uri:  "https://example.org/report.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewA2AServer(mockAgentCard, mockTaskHandler, tt.opts...)
+			response := doJSONRPC(t, server, models.MethodMessageSend, fileURIMessage(tt.uri))
+
+			if tt.allowed {
+				if response.Error != nil {
+					t.Errorf("Expected %s to be accepted, got %v", tt.uri, response.Error)
+				}
+				return
+			}
+			if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+				t.Fatalf("Expected InvalidParams for %s, got %v", tt.uri, response.Error)
+			}
+			if !strings.Contains(response.Error.Message, "part 0") {
+				t.Errorf("Expected the error to name the part, got %q", response.Error.Message)
+			}
+		})
+	}
+}
@@ -1,5 +1,17 @@
 package server
 
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"a2a/models"
+)
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -7,3 +19,110 @@ func stringPtr(s string) *string {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// parseParams decodes the params of a JSON-RPC request into a T. Params
+// arrive as generic JSON values, so they are re-encoded and decoded into the
+// method's parameter type.
+func parseParams[T any](req *models.JSONRPCRequest) (*T, error) {
+	data, err := json.Marshal(req.Params)
+	if err != nil {
+		return nil, err
+	}
+	var params T
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// newTaskStatus returns a status in the given state stamped with the current time
+func newTaskStatus(state models.TaskState) models.TaskStatus {
+	now := time.Now().UTC()
+	return models.TaskStatus{
+		State:     state,
+		Timestamp: &now,
+	}
+}
+
+// stampStatus records the current time on a status reported by a handler
+func stampStatus(status *models.TaskStatus) {
+	now := time.Now().UTC()
+	status.Timestamp = &now
+}
+
+// stampMessage records the current time on a message received from a client
+func stampMessage(message *models.Message) {
+	now := time.Now().UTC()
+	message.Timestamp = &now
+}
+
+// mergeMetadata returns the task metadata with a request's metadata applied
+// on top. The result is a new map, so stored metadata is never modified.
+func mergeMetadata(task, request map[string]interface{}) map[string]interface{} {
+	if len(request) == 0 {
+		return task
+	}
+	merged := maps.Clone(task)
+	if merged == nil {
+		merged = make(map[string]interface{}, len(request))
+	}
+	maps.Copy(merged, request)
+	return merged
+}
+
+// isJSONContentType reports whether a request Content-Type header allows the
+// body to be read as JSON. Requests without the header are accepted.
+func isJSONContentType(header string) bool {
+	if header == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	return err == nil && mediaType == "application/json"
+}
+
+// acceptsEventStream reports whether an Accept header allows a Server-Sent
+// Events response. Requests without the header accept anything.
+func acceptsEventStream(header string) bool {
+	if header == "" {
+		return true
+	}
+	for _, accepted := range strings.Split(header, ",") {
+		mediaType, _, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/event-stream", "text/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// idToString converts a JSON-RPC request ID into its string form.
+// The spec allows string, number, or null IDs; numbers decode to float64.
+func idToString(v interface{}) string {
+	switch id := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case json.Number:
+		return id.String()
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// taskIDFromParams extracts the task ID from decoded JSON-RPC params for
+// logging, returning "" when there is none
+func taskIDFromParams(params interface{}) string {
+	m, ok := params.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := m["id"].(string)
+	return id
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// WithTaskTTL evicts tasks that have been completed, canceled or failed for
+// longer than ttl, together with their history, push notification config and
+// replay buffer. Cleanup runs in the background while the server is serving.
+// By default tasks are kept forever.
+func WithTaskTTL(ttl time.Duration) Option {
+	return func(s *A2AServer) {
+		s.taskTTL = ttl
+	}
+}
+
+// WithCleanupInterval sets how often expired tasks are looked for when
+// WithTaskTTL is used. Defaults to one minute.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(s *A2AServer) {
+		s.cleanupInterval = interval
+	}
+}
+
+// runJanitor removes expired tasks every cleanup interval until ctx is done
+func (s *A2AServer) runJanitor(ctx context.Context) {
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpiredTasks()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// removeExpiredTasks deletes terminal tasks whose last status is older than the TTL
+func (s *A2AServer) removeExpiredTasks() {
+	tasks, err := s.taskStore.List()
+	if err != nil {
+		s.logger.Error("listing tasks for cleanup failed", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, listed := range tasks {
+		// The task may have been continued since it was listed
+		task, exists, err := s.taskStore.Get(listed.ID)
+		if err != nil || !exists {
+			continue
+		}
+		if _, running := s.cancelFuncs[task.ID]; running {
+			continue
+		}
+		if !task.Status.State.IsTerminal() || task.Status.Timestamp == nil || now.Sub(*task.Status.Timestamp) < s.taskTTL {
+			continue
+		}
+
+		if err := s.taskStore.Delete(task.ID); err != nil {
+			s.logger.Error("removing expired task failed", "task_id", task.ID, "error", err)
+			continue
+		}
+		if err := s.pushConfigs.DeletePushConfig(task.ID); err != nil {
+			s.logger.Error("removing expired push notification config failed", "task_id", task.ID, "error", err)
+		}
+		delete(s.replays, task.ID)
+		s.logger.Info("task expired", "task_id", task.ID, "state", task.Status.State)
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestRemoveExpiredTasks(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if task.ID == "waiting" {
+			return mockInputRequiredTaskHandler(ctx, task, message)
+		}
+		return mockTaskHandler(ctx, task, message)
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithTaskTTL(time.Hour))
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	for _, id := range []string{"done", "waiting"} {
+		doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: id, Message: message})
+	}
+	doJSONRPC(t, server, models.MethodTasksPushNotificationSet, models.TaskPushNotificationConfig{
+		ID:                     "done",
+		PushNotificationConfig: models.PushNotificationConfig{URL: "https://example.com/hook"},
+	})
+
+	getTask := func(id string) models.JSONRPCResponse {
+		return doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: id}})
+	}
+
+	// Nothing has expired yet
+	server.removeExpiredTasks()
+	if response := getTask("done"); response.Error != nil {
+		t.Fatalf("Expected task to survive before its TTL, got %v", response.Error)
+	}
+
+	server.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	server.removeExpiredTasks()
+
+	if response := getTask("done"); response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected expired task to be gone, got %+v", response.Error)
+	}
+	if _, exists, _ := server.pushConfigs.PushConfig("done"); exists {
+		t.Error("Expected expired task's push config to be gone")
+	}
+
+	// Tasks awaiting input are not terminal and never expire
+	if response := getTask("waiting"); response.Error != nil {
+		t.Errorf("Expected non-terminal task to remain, got %v", response.Error)
+	}
+}
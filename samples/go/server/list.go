@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"a2a/models"
+)
+
+const (
+	// defaultListLimit is the page size used when tasks/list omits a limit
+	defaultListLimit = 20
+	// maxListLimit caps the page size a client may ask for
+	maxListLimit = 100
+)
+
+// listedTask pairs a task with the time it was created, which orders the list
+type listedTask struct {
+	task    *models.Task
+	created time.Time
+}
+
+// before reports whether t sorts ahead of other: newest first, ties broken by ID
+func (t listedTask) before(created time.Time, id string) bool {
+	if !t.created.Equal(created) {
+		return t.created.After(created)
+	}
+	return t.task.ID < id
+}
+
+// handleTaskList handles the tasks/list method. Tasks are returned most
+// recently created first, without their message or status history. The
+// cursor records the last task of a page, so pages stay consistent while new
+// tasks are created.
+func (s *A2AServer) handleTaskList(w http.ResponseWriter, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.ListTasksParams](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+		return
+	}
+
+	limit := params.Limit
+	if limit < 0 {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+		return
+	}
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+
+	var after *listedTask
+	if params.Cursor != "" {
+		created, taskID, ok := decodeListCursor(params.Cursor)
+		if !ok {
+			s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid cursor")
+			return
+		}
+		after = &listedTask{task: &models.Task{ID: taskID}, created: created}
+	}
+
+	tasks, err := s.listTasks(*params)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+
+	result := models.TaskList{Tasks: []models.Task{}}
+	for i, t := range tasks {
+		if after != nil && !after.before(t.created, t.task.ID) {
+			continue
+		}
+		if len(result.Tasks) == limit {
+			if i > 0 {
+				last := tasks[i-1]
+				result.NextCursor = encodeListCursor(last.created, last.task.ID)
+			}
+			break
+		}
+		task := *t.task
+		task.History = nil
+		task.StatusHistory = nil
+		result.Tasks = append(result.Tasks, task)
+	}
+
+	s.sendResponse(w, id, result)
+}
+
+// listTasks returns the stored tasks matching the state and session filters
+// of params, in list order
+func (s *A2AServer) listTasks(params models.ListTasksParams) ([]listedTask, error) {
+	tasks, err := s.taskStore.List()
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]listedTask, 0, len(tasks))
+	for _, task := range tasks {
+		if params.State != "" && task.Status.State != params.State {
+			continue
+		}
+		if params.SessionID != "" && (task.SessionID == nil || *task.SessionID != params.SessionID) {
+			continue
+		}
+		transitions, err := s.taskStore.Transitions(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		var created time.Time
+		if len(transitions) > 0 && transitions[0].Timestamp != nil {
+			created = *transitions[0].Timestamp
+		}
+		listed = append(listed, listedTask{task: task, created: created})
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].before(listed[j].created, listed[j].task.ID)
+	})
+	return listed, nil
+}
+
+// encodeListCursor builds the opaque cursor pointing just past the given task
+func encodeListCursor(created time.Time, taskID string) string {
+	raw := created.Format(time.RFC3339Nano) + "|" + taskID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeListCursor reverses encodeListCursor
+func decodeListCursor(cursor string) (time.Time, string, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	timestamp, taskID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	created, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return created, taskID, true
+}
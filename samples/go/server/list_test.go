@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"a2a/models"
+)
+
+// listTasks calls tasks/list and decodes the page
+func listTasks(t *testing.T, server *A2AServer, params models.ListTasksParams) models.TaskList {
+	t.Helper()
+
+	response := doJSONRPC(t, server, models.MethodTasksList, params)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+	var list models.TaskList
+	decodeResult(t, response, &list)
+	return list
+}
+
+// newListTestServer creates tasks working-1..3 left working and done-1 completed, in that order
+func newListTestServer(t *testing.T) *A2AServer {
+	t.Helper()
+
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if task.ID != "done-1" {
+			return task, nil
+		}
+		return mockTaskHandler(ctx, task, message)
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	for _, id := range []string{"working-1", "working-2", "working-3", "done-1"} {
+		doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: id, Message: message})
+	}
+	return server
+}
+
+func TestTaskListFilterByState(t *testing.T) {
+	server := newListTestServer(t)
+
+	list := listTasks(t, server, models.ListTasksParams{State: models.TaskStateWorking})
+	if len(list.Tasks) != 3 {
+		t.Fatalf("Expected 3 working tasks, got %d", len(list.Tasks))
+	}
+	for _, task := range list.Tasks {
+		if task.Status.State != models.TaskStateWorking {
+			t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, task.Status.State)
+		}
+		if len(task.History) != 0 {
+			t.Errorf("Expected no history in list, got %d messages", len(task.History))
+		}
+	}
+	if list.NextCursor != "" {
+		t.Errorf("Expected no next cursor, got %q", list.NextCursor)
+	}
+}
+
+func TestTaskListPagination(t *testing.T) {
+	server := newListTestServer(t)
+
+	first := listTasks(t, server, models.ListTasksParams{Limit: 3})
+	if len(first.Tasks) != 3 || first.NextCursor == "" {
+		t.Fatalf("Expected 3 tasks and a cursor, got %d tasks and cursor %q", len(first.Tasks), first.NextCursor)
+	}
+
+	// Tasks created after the first page must not shift the second
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "late",
+		Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+
+	second := listTasks(t, server, models.ListTasksParams{Limit: 3, Cursor: first.NextCursor})
+	if len(second.Tasks) != 1 || second.NextCursor != "" {
+		t.Fatalf("Expected 1 task and no cursor, got %d tasks and cursor %q", len(second.Tasks), second.NextCursor)
+	}
+
+	var ids []string
+	for _, task := range append(first.Tasks, second.Tasks...) {
+		ids = append(ids, task.ID)
+	}
+	expected := []string{"done-1", "working-3", "working-2", "working-1"}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected newest first %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestTaskListInvalidCursor(t *testing.T) {
+	server := newListTestServer(t)
+
+	response := doJSONRPC(t, server, models.MethodTasksList, models.ListTasksParams{Cursor: "not a cursor"})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected invalid params error, got %+v", response.Error)
+	}
+}
+
+func TestTaskListFilterBySession(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	for id, session := range map[string]string{"a": "session-1", "b": "session-1", "c": "session-2"} {
+		doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: id, SessionID: stringPtr(session), Message: message})
+	}
+	// Continuing a task without a session keeps the one it started in
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "a", Message: message})
+
+	list := listTasks(t, server, models.ListTasksParams{SessionID: "session-1"})
+	if len(list.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks in session-1, got %d", len(list.Tasks))
+	}
+	for _, task := range list.Tasks {
+		if task.SessionID == nil || *task.SessionID != "session-1" {
+			t.Errorf("Expected session-1, got %v", task.SessionID)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"a2a/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors enabled by WithMetrics. A nil
+// *metrics records nothing, so call sites need not check whether metrics
+// are enabled.
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	streams         prometheus.Gauge
+}
+
+// WithMetrics exposes Prometheus metrics on /metrics: request counts by
+// method and result, task handler durations, and in-flight streams
+func WithMetrics() Option {
+	return func(s *A2AServer) {
+		s.metrics = newMetrics()
+	}
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "a2a_requests_total",
+			Help: "JSON-RPC requests handled, by method and result.",
+		}, []string{"method", "result"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "a2a_handler_duration_seconds",
+			Help:    "Time spent in task handlers, by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		streams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "a2a_streams_in_flight",
+			Help: "Streaming connections currently open.",
+		}),
+	}
+	m.registry.MustRegister(m.requests, m.handlerDuration, m.streams)
+	return m
+}
+
+// handler serves the collected metrics
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeRequest counts a handled request; result is "success" or "error"
+func (m *metrics) observeRequest(method, result string) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(method, result).Inc()
+}
+
+// observeHandler records how long a task handler ran
+func (m *metrics) observeHandler(method string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.handlerDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// streamStarted tracks an open streaming connection, returning a func that
+// must be called when it closes
+func (m *metrics) streamStarted() func() {
+	if m == nil {
+		return func() {}
+	}
+	m.streams.Inc()
+	return m.streams.Dec
+}
+
+// resultRecorder remembers whether a JSON-RPC error was sent in reply, and
+// its code
+type resultRecorder struct {
+	http.ResponseWriter
+	failed bool
+	code   models.ErrorCode
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *resultRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// flushingResultRecorder is a resultRecorder for writers that can stream
+type flushingResultRecorder struct {
+	*resultRecorder
+	http.Flusher
+}
+
+// recordResult wraps w so that sendError can mark the request failed,
+// preserving w's ability to flush
+func recordResult(w http.ResponseWriter) (http.ResponseWriter, *resultRecorder) {
+	rec := &resultRecorder{ResponseWriter: w}
+	if f, ok := w.(http.Flusher); ok {
+		return flushingResultRecorder{rec, f}, rec
+	}
+	return rec, rec
+}
+
+// markFailed notes on w, if it records results, that an error with the
+// given code was sent
+func markFailed(w http.ResponseWriter, code models.ErrorCode) {
+	var rec *resultRecorder
+	switch w := w.(type) {
+	case *resultRecorder:
+		rec = w
+	case flushingResultRecorder:
+		rec = w.resultRecorder
+	default:
+		return
+	}
+	rec.failed = true
+	rec.code = code
+}
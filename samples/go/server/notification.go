@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"a2a/models"
+)
+
+// rpcRequest is a JSON-RPC request that remembers whether it had an id
+// member. Only requests without one are notifications; an explicit null id
+// still gets a response.
+type rpcRequest struct {
+	models.JSONRPCRequest
+	hasID bool
+}
+
+func (r *rpcRequest) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.JSONRPCRequest); err != nil {
+		return err
+	}
+	// A RawMessage keeps a null id as the literal null rather than nil
+	var fields struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	r.hasID = fields.ID != nil
+	return nil
+}
+
+// notificationWriter stands in for the response to a JSON-RPC notification,
+// which must not be answered. Methods run as usual but whatever they write is
+// discarded.
+type notificationWriter struct {
+	header http.Header
+}
+
+func (w *notificationWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *notificationWriter) WriteHeader(int) {}
+
+func (w *notificationWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"a2a/models"
+)
+
+// pushTimeout bounds each push notification delivery
+const pushTimeout = 10 * time.Second
+
+// pushTokenHeader carries the token from the push notification config so
+// that webhooks can verify notifications came from this agent
+const pushTokenHeader = "X-A2A-Notification-Token"
+
+// PushConfigStore persists the push notification config of each task. A
+// TaskStore that also implements PushConfigStore keeps configs alongside its
+// tasks, so they survive restarts as the tasks do; with any other TaskStore
+// configs are kept in memory.
+type PushConfigStore interface {
+	// SavePushConfig creates or replaces a task's push notification config
+	SavePushConfig(taskID string, config models.PushNotificationConfig) error
+	// PushConfig returns a task's push notification config and whether it has one
+	PushConfig(taskID string) (models.PushNotificationConfig, bool, error)
+	// DeletePushConfig removes a task's push notification config, if any
+	DeletePushConfig(taskID string) error
+}
+
+// memoryPushConfigs is the PushConfigStore used with task stores that don't
+// provide one
+type memoryPushConfigs struct {
+	mu      sync.RWMutex
+	configs map[string]models.PushNotificationConfig
+}
+
+func newMemoryPushConfigs() *memoryPushConfigs {
+	return &memoryPushConfigs{configs: make(map[string]models.PushNotificationConfig)}
+}
+
+func (m *memoryPushConfigs) SavePushConfig(taskID string, config models.PushNotificationConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configs[taskID] = config
+	return nil
+}
+
+func (m *memoryPushConfigs) PushConfig(taskID string) (models.PushNotificationConfig, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	config, exists := m.configs[taskID]
+	return config, exists, nil
+}
+
+func (m *memoryPushConfigs) DeletePushConfig(taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.configs, taskID)
+	return nil
+}
+
+// notifyPush POSTs task to the webhook configured for it, if any, in the
+// background so that the request that changed the task is not held up. The
+// caller must hold s.mu.
+func (s *A2AServer) notifyPush(task *models.Task) {
+	config, exists, err := s.pushConfigs.PushConfig(task.ID)
+	if err != nil {
+		s.logger.Error("loading push notification config failed", "task_id", task.ID, "error", err)
+		return
+	}
+	if !exists {
+		return
+	}
+	body, err := json.Marshal(task)
+	if err != nil {
+		s.logger.Error("encoding push notification failed", "task_id", task.ID, "error", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(body))
+		if err != nil {
+			s.logger.Error("push notification failed", "task_id", task.ID, "error", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if config.Token != nil {
+			req.Header.Set(pushTokenHeader, *config.Token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			s.logger.Error("push notification failed", "task_id", task.ID, "error", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			s.logger.Error("push notification rejected", "task_id", task.ID, "status", resp.StatusCode)
+		}
+	}()
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"a2a/models"
+)
+
+// defaultReplayBufferSize covers the events a client is likely to miss while
+// it reconnects
+const defaultReplayBufferSize = 64
+
+// WithReplayBufferSize sets how many of each task's most recent stream
+// events are kept so that a client reconnecting through tasks/resubscribe
+// with a Last-Event-ID header is sent the events it missed. Events older
+// than the buffer are lost to such clients. A size of zero or less turns
+// replay off. Defaults to 64.
+func WithReplayBufferSize(n int) Option {
+	return func(s *A2AServer) {
+		s.replayBufferSize = max(n, 0)
+	}
+}
+
+// defaultReplayRetention gives clients of a finished task time to reconnect
+// and collect the events they missed
+const defaultReplayRetention = 5 * time.Minute
+
+// WithReplayRetention sets how long a task's replay buffer is kept once the
+// task has finished. Clients reconnecting later are sent only the task's
+// final status. Defaults to 5 minutes.
+func WithReplayRetention(d time.Duration) Option {
+	return func(s *A2AServer) {
+		s.replayRetention = d
+	}
+}
+
+// streamEvent is a stream update with the SSE event ID it was published
+// under. An ID of zero means the update has none, like the status snapshot a
+// resubscribe starts with.
+type streamEvent struct {
+	id    uint64
+	event any
+}
+
+// replayBuffer numbers a task's stream events and keeps the most recent of
+// them. IDs keep increasing across runs of the task, so a Last-Event-ID
+// from an earlier run is never mistaken for a newer event.
+type replayBuffer struct {
+	mu     sync.Mutex
+	size   int
+	lastID uint64
+	events []streamEvent
+}
+
+// add assigns event the next ID and keeps it, evicting the oldest event when
+// the buffer is full
+func (b *replayBuffer) add(event any) streamEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := streamEvent{id: b.lastID, event: event}
+	if b.size > 0 {
+		if len(b.events) == b.size {
+			b.events = b.events[1:]
+		}
+		b.events = append(b.events, e)
+	}
+	return e
+}
+
+// since returns the kept events published after the event with ID lastID
+func (b *replayBuffer) since(lastID uint64) []streamEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := slices.IndexFunc(b.events, func(e streamEvent) bool { return e.id > lastID })
+	if i < 0 {
+		return nil
+	}
+	return slices.Clone(b.events[i:])
+}
+
+// replayBuffer returns the replay buffer of a task, creating it on the
+// task's first stream. The caller must hold s.mu.
+func (s *A2AServer) replayBuffer(taskID string) *replayBuffer {
+	buf, ok := s.replays[taskID]
+	if !ok {
+		buf = &replayBuffer{size: s.replayBufferSize}
+		s.replays[taskID] = buf
+	}
+	return buf
+}
+
+// retireReplay drops a finished task's replay buffer once the retention
+// window passes, so that buffers don't pile up for every task ever streamed.
+// The caller must hold s.mu.
+func (s *A2AServer) retireReplay(taskID string) {
+	buf, ok := s.replays[taskID]
+	if !ok {
+		return
+	}
+	time.AfterFunc(s.replayRetention, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.replays[taskID] == buf {
+			delete(s.replays, taskID)
+		}
+	})
+}
+
+// lastEventID reads the Last-Event-ID header of a reconnecting client,
+// reporting false if there is none or it isn't one of ours
+func lastEventID(r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.Header.Get(models.LastEventIDHeader), 10, 64)
+	return id, err == nil
+}
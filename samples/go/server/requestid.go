@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"a2a/models"
+)
+
+// maxRequestIDLength bounds caller-supplied request IDs so that they can't
+// bloat logs; longer IDs are replaced with a generated one
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the correlation ID of the HTTP request that
+// ctx belongs to, or "" if there is none. Task handlers can use it to tag
+// their own logs.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID reads the request's X-Request-ID header, generating an ID
+// when it is missing or too long, echoes it in the response headers and
+// stores it in the request context
+func withRequestID(w http.ResponseWriter, r *http.Request) (*http.Request, string) {
+	id := r.Header.Get(models.RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = models.NewRequestID()
+	}
+	w.Header().Set(models.RequestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)), id
+}
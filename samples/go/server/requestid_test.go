@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		seen = RequestIDFromContext(ctx)
+		// Each subtest sends to the same task, so it must stay open
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "message/send", "params": {"id": "t", "message": {"role": "user", "parts": [{"type": "text", "text": "Hello"}]}}}`
+
+	tests := []struct {
+		name     string
+		sent     string
+		wantEcho bool
+	}{
+		{name: "echoes the caller's ID", sent: "abc-123", wantEcho: true},
+		{name: "generates a missing ID"},
+		{name: "replaces an oversized ID", sent: strings.Repeat("x", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.sent != "" {
+				req.Header.Set(models.RequestIDHeader, tt.sent)
+			}
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			got := w.Header().Get(models.RequestIDHeader)
+			if got == "" {
+				t.Fatal("expected a request ID in the response headers")
+			}
+			if tt.wantEcho != (got == tt.sent) {
+				t.Errorf("got request ID %q for sent ID %q", got, tt.sent)
+			}
+			if seen != got {
+				t.Errorf("expected handler context to carry %q, got %q", got, seen)
+			}
+		})
+	}
+}
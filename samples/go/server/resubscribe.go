@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"a2a/models"
+)
+
+// finishStream marks a streaming task as no longer running and ends the
+// subscriptions to its event bus. The replay buffer of a task that finished
+// is retired.
+func (s *A2AServer) finishStream(taskID string, bus *eventBus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cancelFuncs, taskID)
+	if s.streams[taskID] == bus {
+		delete(s.streams, taskID)
+	}
+	bus.close()
+
+	if task, exists, err := s.taskStore.Get(taskID); err == nil && exists && task.Status.State.IsTerminal() {
+		s.retireReplay(taskID)
+	}
+}
+
+// handleResubscribe handles the tasks/resubscribe method. It streams the
+// current status of a running task followed by its remaining events, or a
+// single final event if the task is no longer running. A client sending
+// Last-Event-ID is instead replayed the events it missed from the task's
+// replay buffer, ending with a final event if the task has finished.
+func (s *A2AServer) handleResubscribe(w http.ResponseWriter, r *http.Request, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.TaskQueryParams](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger.With("method", models.MethodTasksResubscribe, "task_id", params.ID)
+
+	s.mu.Lock()
+	task, exists, err := s.taskStore.Get(params.ID)
+	if err != nil || !exists {
+		s.mu.Unlock()
+		if err != nil {
+			s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		} else {
+			s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+		}
+		return
+	}
+
+	lastID, resuming := lastEventID(r)
+	bus, running := s.streams[params.ID]
+	var sub *subscriber
+	var missed []streamEvent
+	switch {
+	case running && resuming:
+		sub, missed = bus.resume(lastID, 0, ctx.Done())
+	case running:
+		sub = bus.subscribe(0, ctx.Done())
+	case resuming:
+		if replay, ok := s.replays[params.ID]; ok {
+			missed = replay.since(lastID)
+		}
+	}
+	s.mu.Unlock()
+
+	flusher := s.startSSE(w)
+
+	updates := make(chan streamEvent, len(missed)+1)
+	for _, e := range missed {
+		updates <- e
+	}
+	// A resuming client already knows the status of a running task, but a
+	// finished task's stream must still end with a final event
+	ended := len(missed) > 0 && isFinalEvent(missed[len(missed)-1].event)
+	if (sub != nil && !resuming) || (sub == nil && !ended) {
+		updates <- streamEvent{event: models.TaskStatusUpdateEvent{
+			ID:     task.ID,
+			Status: task.Status,
+			Final:  boolPtr(sub == nil),
+		}}
+	}
+
+	if sub == nil {
+		close(updates)
+		s.pumpSSE(ctx, w, flusher, updates, log)
+		return
+	}
+	defer bus.unsubscribe(sub)
+
+	go forwardEvents(ctx, sub.events, updates)
+	s.pumpSSE(ctx, w, flusher, updates, log)
+}
+
+// forwardEvents copies events into updates, closing updates when events
+// closes. It also returns once ctx is done: the subscription is dropped when
+// the client goes away, so events may then never be closed.
+func forwardEvents(ctx context.Context, events <-chan streamEvent, updates chan<- streamEvent) {
+	defer close(updates)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			select {
+			case updates <- event:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
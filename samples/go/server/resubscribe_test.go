@@ -0,0 +1,398 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestA2AServer_ResubscribeAfterDisconnect(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	// The handler gives up once its context ends, as well-behaved handlers
+	// do, so it only completes if the disconnect leaves its context alone
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(handlerStarted)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID: "test-task-1",
+			Message: models.Message{
+				Role:  models.RoleUser,
+				Parts: []models.Part{{Text: stringPtr("Hello")}},
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	streamed := make(chan struct{})
+	go func() {
+		server.ServeHTTP(httptest.NewRecorder(), req)
+		close(streamed)
+	}()
+
+	// Drop the original stream while the handler is still running
+	<-handlerStarted
+	cancel()
+	<-streamed
+
+	resubscribed := make(chan *httptest.ResponseRecorder)
+	go func() {
+		resubscribed <- doResubscribe(t, server, "test-task-1")
+	}()
+
+	waitForSubscriber(t, server, "test-task-1")
+	close(release)
+
+	var w *httptest.ResponseRecorder
+	select {
+	case w = <-resubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Resubscribe did not finish after the task completed")
+	}
+
+	results := streamResults(t, w.Body.String())
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %s", len(results), w.Body.String())
+	}
+
+	var current, final models.TaskStatusUpdateEvent
+	json.Unmarshal(results[0], &current)
+	json.Unmarshal(results[1], &final)
+
+	if current.Status.State != models.TaskStateWorking || current.Final == nil || *current.Final {
+		t.Errorf("Expected non-final working event, got %+v", current)
+	}
+	if final.Status.State != models.TaskStateCompleted || final.Final == nil || !*final.Final {
+		t.Errorf("Expected final completed event, got %+v", final)
+	}
+}
+
+func TestA2AServer_ResubscribeFinishedTask(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	doStream(t, server, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	w := doResubscribe(t, server, "test-task-1")
+
+	results := streamResults(t, w.Body.String())
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(results))
+	}
+
+	var event models.TaskStatusUpdateEvent
+	json.Unmarshal(results[0], &event)
+	if event.Status.State != models.TaskStateCompleted || event.Final == nil || !*event.Final {
+		t.Errorf("Expected final completed event, got %+v", event)
+	}
+}
+
+// chunkStreamingHandler streams each word of the message as an artifact chunk
+func chunkStreamingHandler(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+	for _, word := range strings.Fields(*message.Parts[0].Text) {
+		events <- models.TaskArtifactUpdateEvent{
+			ID:       task.ID,
+			Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr(word)}}},
+		}
+	}
+	return nil
+}
+
+func TestA2AServer_ResubscribeLastEventID(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, nil, WithStreamingHandler(chunkStreamingHandler))
+	w := doStream(t, server, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "one two three"),
+	})
+
+	// submitted, working, three chunks and the final status, numbered in order
+	var ids []string
+	for _, frame := range sseFrames(t, w.Body.String()) {
+		ids = append(ids, frame.id)
+	}
+	if strings.Join(ids, ",") != "1,2,3,4,5,6" {
+		t.Fatalf("Expected events numbered 1 to 6, got %v", ids)
+	}
+
+	// A client that saw the first chunk is sent only what came after it
+	req := resubscribeRequest("test-task-1")
+	req.Header.Set(models.LastEventIDHeader, "3")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	frames := sseFrames(t, w.Body.String())
+	ids = nil
+	for _, frame := range frames {
+		ids = append(ids, frame.id)
+	}
+	if strings.Join(ids, ",") != "4,5,6" {
+		t.Fatalf("Expected events 4 to 6 to be replayed, got %v: %s", ids, w.Body.String())
+	}
+	var response struct{ Result models.TaskStatusUpdateEvent }
+	json.Unmarshal([]byte(frames[2].data), &response)
+	if final := response.Result; final.Status.State != models.TaskStateCompleted || final.Final == nil || !*final.Final {
+		t.Errorf("Expected the replay to end with the final completed event, got %+v", final)
+	}
+}
+
+func TestA2AServer_ResubscribeLastEventIDEvicted(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, nil, WithStreamingHandler(chunkStreamingHandler), WithReplayBufferSize(2))
+	doStream(t, server, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "one two three"),
+	})
+
+	req := resubscribeRequest("test-task-1")
+	req.Header.Set(models.LastEventIDHeader, "1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	// Only the events still buffered can be replayed
+	var ids []string
+	for _, frame := range sseFrames(t, w.Body.String()) {
+		ids = append(ids, frame.id)
+	}
+	if strings.Join(ids, ",") != "5,6" {
+		t.Errorf("Expected the 2 buffered events to be replayed, got %v", ids)
+	}
+}
+
+func TestReplayRetention(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, nil, WithStreamingHandler(chunkStreamingHandler), WithReplayRetention(10*time.Millisecond))
+	doStream(t, server, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "one two three"),
+	})
+
+	// The finished task's buffer is dropped once the retention passes
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.RLock()
+		_, kept := server.replays["test-task-1"]
+		server.mu.RUnlock()
+		if !kept {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the replay buffer to be dropped after the retention window")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Reconnecting clients still learn how the task ended
+	req := resubscribeRequest("test-task-1")
+	req.Header.Set(models.LastEventIDHeader, "1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	results := streamResults(t, w.Body.String())
+	var final models.TaskStatusUpdateEvent
+	json.Unmarshal(results[len(results)-1], &final)
+	if len(results) != 1 || final.Status.State != models.TaskStateCompleted || final.Final == nil || !*final.Final {
+		t.Errorf("Expected a single final completed event, got %s", w.Body.String())
+	}
+}
+
+func TestA2AServer_ResubscribeLastEventIDRunning(t *testing.T) {
+	chunkSent := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{ID: task.ID, Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr("missed")}}}}
+		close(chunkSent)
+		<-release
+		events <- models.TaskArtifactUpdateEvent{ID: task.ID, Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr("live")}}}}
+		return nil
+	}
+	server := NewA2AServer(mockAgentCard, nil, WithStreamingHandler(handler))
+
+	// The original stream drops after the working event, missing the chunk
+	ctx, cancel := context.WithCancel(context.Background())
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"message/stream","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"Hello"}]}}}`)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		server.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-chunkSent
+	cancel()
+	<-streamed
+
+	resubscribed := make(chan *httptest.ResponseRecorder)
+	go func() {
+		req := resubscribeRequest("test-task-1")
+		req.Header.Set(models.LastEventIDHeader, "2")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		resubscribed <- w
+	}()
+	waitForSubscriber(t, server, "test-task-1")
+	close(release)
+
+	var w *httptest.ResponseRecorder
+	select {
+	case w = <-resubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Resubscribe did not finish after the task completed")
+	}
+
+	// The missed chunk is replayed, then the stream carries on live
+	var ids []string
+	for _, frame := range sseFrames(t, w.Body.String()) {
+		ids = append(ids, frame.id)
+	}
+	if strings.Join(ids, ",") != "3,4,5" {
+		t.Errorf("Expected events 3 to 5 without a status snapshot, got %v: %s", ids, w.Body.String())
+	}
+}
+
+func TestA2AServer_ResubscribeClientDisconnects(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(handlerStarted)
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	defer close(release)
+
+	go doStream(t, server, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	<-handlerStarted
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		server.ServeHTTP(httptest.NewRecorder(), resubscribeRequest("test-task").WithContext(ctx))
+	}()
+	waitForSubscribers(t, server, "test-task", 2)
+
+	// Disconnect while the task is still running
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("Resubscribe kept streaming after the client disconnected")
+	}
+
+	server.mu.RLock()
+	bus := server.streams["test-task"]
+	server.mu.RUnlock()
+	// Only the original stream is left
+	if n := bus.subscribers(); n != 1 {
+		t.Errorf("Expected the resubscription to be dropped, %d subscribers left", n)
+	}
+
+	// Nothing is left waiting on the dead connection's events
+	deadline := time.Now().Add(time.Second)
+	for leaked := true; leaked; {
+		buf := make([]byte, 1<<20)
+		leaked = strings.Contains(string(buf[:runtime.Stack(buf, true)]), "server.forwardEvents")
+		if leaked && time.Now().After(deadline) {
+			t.Fatal("Expected no goroutine left forwarding events to the disconnected client")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestA2AServer_ResubscribeUnknownTask(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	response := doJSONRPC(t, server, models.MethodTasksResubscribe, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "missing"},
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected task not found error, got %v", response.Error)
+	}
+}
+
+// doResubscribe sends a tasks/resubscribe request and returns the recorded response
+func doResubscribe(t *testing.T, server *A2AServer, taskID string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, resubscribeRequest(taskID))
+	return w
+}
+
+// resubscribeRequest builds a tasks/resubscribe request for the task
+func resubscribeRequest(taskID string) *http.Request {
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "2",
+			},
+		},
+		Method: models.MethodTasksResubscribe,
+		Params: models.TaskQueryParams{
+			TaskIDParams: models.TaskIDParams{ID: taskID},
+		},
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	return req
+}
+
+// waitForSubscriber blocks until a resubscribed client is attached to a task
+// whose original stream has disconnected
+func waitForSubscriber(t *testing.T, server *A2AServer, taskID string) {
+	t.Helper()
+	waitForSubscribers(t, server, taskID, 1)
+}
+
+// waitForSubscribers blocks until n connections, counting the stream that
+// started the task, are subscribed to the task's events
+func waitForSubscribers(t *testing.T, server *A2AServer, taskID string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		server.mu.RLock()
+		bus := server.streams[taskID]
+		server.mu.RUnlock()
+		if bus != nil && bus.subscribers() >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for subscriber")
+}
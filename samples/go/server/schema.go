@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"a2a/models"
+)
+
+// defaultDataMode is the input mode assumed for data parts whose metadata
+// does not name a mimeType
+const defaultDataMode = "application/json"
+
+// WithInputSchema validates data parts sent in the given input mode against
+// a JSON Schema before the handler runs. A data part's mode is the mimeType
+// in its metadata, or application/json if none is set. Parts that fail
+// validation are rejected with InvalidParams. Once any schema is registered,
+// data parts in a mode the agent card does not declare are rejected with
+// ContentTypeNotSupported. An invalid schema makes Start and Serve fail.
+func WithInputSchema(mode string, schema []byte) Option {
+	return func(s *A2AServer) {
+		compiled, err := compileSchema(mode, schema)
+		if err != nil {
+			s.schemaErr = fmt.Errorf("input schema for %q: %w", mode, err)
+			return
+		}
+		if s.inputSchemas == nil {
+			s.inputSchemas = make(map[string]*jsonschema.Schema)
+		}
+		s.inputSchemas[mode] = compiled
+	}
+}
+
+// compileSchema compiles a JSON Schema document, naming it after mode
+func compileSchema(mode string, schema []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+	url := "mem:///" + mode
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// validateDataParts checks the data parts of message against the registered
+// input schemas, returning the JSON-RPC error code and message to reply with
+func (s *A2AServer) validateDataParts(message models.Message) (models.ErrorCode, string, bool) {
+	if len(s.inputSchemas) == 0 {
+		return 0, "", true
+	}
+
+	for i, part := range message.Parts {
+		if part.Type != models.PartTypeData {
+			continue
+		}
+
+		mode := defaultDataMode
+		if mimeType, ok := part.Metadata["mimeType"].(string); ok && mimeType != "" {
+			mode = mimeType
+		}
+
+		schema, ok := s.inputSchemas[mode]
+		if !ok {
+			if !s.acceptsInputMode(mode) {
+				return models.ErrorCodeContentTypeNotSupported, fmt.Sprintf("Input mode %q is not supported", mode), false
+			}
+			continue
+		}
+
+		// Round-trip through the validator's decoder so numbers are
+		// represented the way it expects
+		data, err := json.Marshal(part.Data)
+		if err != nil {
+			return models.ErrorCodeInvalidParams, "Invalid parameters", false
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return models.ErrorCodeInvalidParams, "Invalid parameters", false
+		}
+		if err := schema.Validate(doc); err != nil {
+			return models.ErrorCodeInvalidParams, fmt.Sprintf("Invalid data in part %d: %v", i, err), false
+		}
+	}
+	return 0, "", true
+}
+
+// acceptsInputMode reports whether the agent card declares mode, either as a
+// default input mode or as an input mode of one of its skills
+func (s *A2AServer) acceptsInputMode(mode string) bool {
+	if slices.Contains(s.agentCard.DefaultInputModes, mode) {
+		return true
+	}
+	for _, skill := range s.agentCard.Skills {
+		if slices.Contains(skill.InputModes, mode) {
+			return true
+		}
+	}
+	return false
+}
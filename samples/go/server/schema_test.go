@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"properties": {"sku": {"type": "string"}, "quantity": {"type": "integer"}},
+	"required": ["sku"]
+}`
+
+func dataMessage(data map[string]interface{}, mimeType string) models.TaskSendParams {
+	part := models.Part{Data: data}
+	if mimeType != "" {
+		part.Metadata = map[string]interface{}{"mimeType": mimeType}
+	}
+	return models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.Message{Role: models.RoleUser, Parts: []models.Part{part}},
+	}
+}
+
+func TestInputSchemaRejectsMissingField(t *testing.T) {
+	called := false
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		called = true
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithInputSchema("application/json", []byte(orderSchema)))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, dataMessage(map[string]interface{}{"quantity": 2}, ""))
+	if response.Error == nil {
+		t.Fatal("Expected error for data missing a required field")
+	}
+	if response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected error code %d, got %d", models.ErrorCodeInvalidParams, response.Error.Code)
+	}
+	if !strings.Contains(response.Error.Message, "sku") {
+		t.Errorf("Expected error to name the missing field, got %q", response.Error.Message)
+	}
+	if called {
+		t.Error("Expected handler not to be called")
+	}
+
+	response = doJSONRPC(t, server, models.MethodMessageSend, dataMessage(map[string]interface{}{"sku": "A-1", "quantity": 2}, ""))
+	if response.Error != nil {
+		t.Fatalf("Expected valid data to be accepted, got %v", response.Error)
+	}
+	if !called {
+		t.Error("Expected handler to be called")
+	}
+}
+
+func TestInputSchemaUnsupportedMode(t *testing.T) {
+	card := mockAgentCard
+	card.DefaultInputModes = []string{"text", "application/json"}
+	server := NewA2AServer(card, mockTaskHandler, WithInputSchema("application/json", []byte(orderSchema)))
+
+	response := doJSONRPC(t, server, models.MethodMessageStream, dataMessage(map[string]interface{}{"sku": "A-1"}, "application/xml"))
+	if response.Error == nil {
+		t.Fatal("Expected error for undeclared input mode")
+	}
+	if response.Error.Code != int(models.ErrorCodeContentTypeNotSupported) {
+		t.Errorf("Expected error code %d, got %d", models.ErrorCodeContentTypeNotSupported, response.Error.Code)
+	}
+}
+
+func TestInvalidInputSchema(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithInputSchema("application/json", []byte(`{"type": 5}`)))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	if err := server.Serve(listener); err == nil {
+		t.Error("Expected Serve to fail with an invalid schema")
+	}
+}
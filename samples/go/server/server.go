@@ -1,200 +1,1248 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"runtime/debug"
 	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"a2a/models"
 )
 
 // TaskHandler is a function type that handles task processing
-type TaskHandler func(task *models.Task, message *models.Message) (*models.Task, error)
+type TaskHandler func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error)
+
+// StreamingTaskHandler is a function type that handles task processing while
+// emitting TaskStatusUpdateEvent and TaskArtifactUpdateEvent values on events.
+// The server owns the channel and closes the stream once the handler returns.
+type StreamingTaskHandler func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error
 
 // A2AServer represents an A2A server instance
 type A2AServer struct {
-	agentCard   models.AgentCard
-	handler     TaskHandler
-	port        int
-	basePath    string
-	taskStore   map[string]*models.Task
-	taskHistory map[string][]*models.Message
-	mu          sync.RWMutex
+	agentCard         models.AgentCard
+	handler           TaskHandler
+	streamingHandler  StreamingTaskHandler
+	port              int
+	basePath          string
+	taskStore         TaskStore
+	pushConfigs       PushConfigStore
+	cancelFuncs       map[string]context.CancelFunc
+	streams           map[string]*eventBus
+	replays           map[string]*replayBuffer
+	httpServer        *http.Server
+	slots             chan struct{}
+	logger            *slog.Logger
+	metrics           *metrics
+	maxBodyBytes      int64
+	readTimeout       time.Duration
+	idleTimeout       time.Duration
+	tlsConfig         *tls.Config
+	corsOrigins       []string
+	middleware        []func(http.Handler) http.Handler
+	taskTTL           time.Duration
+	cleanupInterval   time.Duration
+	now               func() time.Time
+	websocket         bool
+	tracer            trace.Tracer
+	heartbeatInterval time.Duration
+	streamBufferSize  int
+	replayBufferSize  int
+	replayRetention   time.Duration
+	flushWindow       time.Duration
+	flushMaxEvents    int
+	handlerTimeout    time.Duration
+	compression       bool
+	idempotentSends   bool
+	skipWorking       bool
+	skillHandlers     map[string]TaskHandler
+	inputSchemas      map[string]*jsonschema.Schema
+	uriPolicy         models.URIPolicy
+	idGenerator       IDGenerator
+	accessLog         bool
+	schemaErr         error
+	mu                sync.RWMutex
+}
+
+// Option configures an A2AServer
+type Option func(*A2AServer)
+
+// WithStreamingHandler sets the handler used for message/stream requests.
+// Without one, streaming requests fall back to the TaskHandler.
+func WithStreamingHandler(handler StreamingTaskHandler) Option {
+	return func(s *A2AServer) {
+		s.streamingHandler = handler
+	}
+}
+
+// WithPort sets the port Start listens on. Defaults to 8080.
+func WithPort(port int) Option {
+	return func(s *A2AServer) {
+		s.port = port
+	}
+}
+
+// WithBasePath sets the path the JSON-RPC endpoint is served on. Defaults to "/".
+func WithBasePath(basePath string) Option {
+	return func(s *A2AServer) {
+		s.basePath = basePath
+	}
+}
+
+// WithTaskStore sets the store used to persist tasks. Defaults to an in-memory store.
+func WithTaskStore(store TaskStore) Option {
+	return func(s *A2AServer) {
+		s.taskStore = store
+	}
+}
+
+// WithMaxConcurrentTasks limits how many task handlers may run at once.
+// Requests arriving while n handlers are busy are rejected with an
+// InternalError so that callers can retry later. By default there is no limit.
+func WithMaxConcurrentTasks(n int) Option {
+	return func(s *A2AServer) {
+		if n > 0 {
+			s.slots = make(chan struct{}, n)
+		} else {
+			s.slots = nil
+		}
+	}
+}
+
+// WithMaxBodyBytes limits the size of JSON-RPC request bodies. Larger
+// requests are rejected with InvalidRequest. Defaults to 4 MiB.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *A2AServer) {
+		s.maxBodyBytes = n
+	}
+}
+
+// WithReadTimeout bounds how long the server waits to read a request,
+// guarding against slow clients holding connections open. Defaults to 30s.
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *A2AServer) {
+		s.readTimeout = d
+	}
+}
+
+// WithIdleTimeout bounds how long an idle keep-alive connection is kept.
+// Defaults to 120s.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *A2AServer) {
+		s.idleTimeout = d
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used by StartTLS and ServeTLS,
+// e.g. to require client certificates for mutual TLS
+func WithTLSConfig(config *tls.Config) Option {
+	return func(s *A2AServer) {
+		s.tlsConfig = config
+	}
+}
+
+// WithIdempotentSends makes message/send return the stored task, without
+// running the handler again, when the task already completed, failed or was
+// canceled. Clients can then safely retry a send whose response was lost.
+// By default such a send is rejected, as finished tasks take no further
+// messages.
+func WithIdempotentSends() Option {
+	return func(s *A2AServer) {
+		s.idempotentSends = true
+	}
+}
+
+// WithoutImplicitWorking stops the server from marking tasks working before
+// calling the handler, leaving the handler to set the state itself. New tasks
+// reach the handler submitted, so an instantaneous handler can complete them
+// directly; continued tasks keep the state they were left in. The handler's
+// transitions are checked as if the task were working, so it may e.g.
+// complete a task that was waiting for input. By default every task is
+// marked working first.
+func WithoutImplicitWorking() Option {
+	return func(s *A2AServer) {
+		s.skipWorking = true
+	}
+}
+
+// WithHandlerTimeout bounds how long a task handler may run. When d passes,
+// the handler's context is canceled, the task is marked failed with a
+// message saying it timed out, and the request fails with an InternalError
+// without waiting for the handler to return. By default there is no limit.
+func WithHandlerTimeout(d time.Duration) Option {
+	return func(s *A2AServer) {
+		s.handlerTimeout = d
+	}
+}
+
+// WithHeartbeatInterval makes streams send an SSE comment whenever d passes
+// without an event, so that proxies and load balancers do not close the
+// connection during long-running tasks. Heartbeats are off by default.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(s *A2AServer) {
+		s.heartbeatInterval = d
+	}
+}
+
+// WithStreamBufferSize sets how many events a stream may queue for a client
+// that reads slower than the handler produces them. When the buffer is full
+// the handler blocks until the client catches up or disconnects; events are
+// never dropped. Defaults to 16.
+func WithStreamBufferSize(n int) Option {
+	return func(s *A2AServer) {
+		s.streamBufferSize = max(n, 0)
+	}
+}
+
+// WithFlushCoalescing batches stream flushes for handlers that emit many
+// small events, such as one per token. Events are written as they arrive but
+// flushed to the client at most once per window, or as soon as maxEvents
+// are pending, whichever comes first; a maxEvents of zero or less leaves only
+// the window. Final events are always flushed immediately. By default every
+// event is flushed as soon as it is written.
+func WithFlushCoalescing(window time.Duration, maxEvents int) Option {
+	return func(s *A2AServer) {
+		s.flushWindow = window
+		s.flushMaxEvents = maxEvents
+	}
+}
+
+// WithLogger sets the logger used to report requests, handler errors, task
+// state transitions and client disconnects. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *A2AServer) {
+		s.logger = logger
+	}
+}
+
+// defaultMaxBodyBytes leaves room for file parts carrying a few megabytes of
+// base64 data
+const defaultMaxBodyBytes = 4 << 20
+
+// defaultStreamBufferSize lets a handler run a few events ahead of a slow client
+const defaultStreamBufferSize = 16
+
+func NewA2AServer(agentCard models.AgentCard, handler TaskHandler, opts ...Option) *A2AServer {
+	s := &A2AServer{
+		agentCard:        agentCard,
+		handler:          handler,
+		port:             8080,
+		basePath:         "/",
+		taskStore:        NewInMemoryTaskStore(),
+		cancelFuncs:      make(map[string]context.CancelFunc),
+		streams:          make(map[string]*eventBus),
+		replays:          make(map[string]*replayBuffer),
+		logger:           slog.New(slog.DiscardHandler),
+		maxBodyBytes:     defaultMaxBodyBytes,
+		streamBufferSize: defaultStreamBufferSize,
+		replayBufferSize: defaultReplayBufferSize,
+		replayRetention:  defaultReplayRetention,
+		readTimeout:      30 * time.Second,
+		idleTimeout:      120 * time.Second,
+		cleanupInterval:  time.Minute,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if store, ok := s.taskStore.(PushConfigStore); ok {
+		s.pushConfigs = store
+	} else {
+		s.pushConfigs = newMemoryPushConfigs()
+	}
+	return s
+}
+
+// agentCardPath is the well-known path where the agent card is published
+const agentCardPath = "/.well-known/agent.json"
+
+// healthPath is where load balancers and orchestrators check liveness
+const healthPath = "/healthz"
+
+// Use adds middleware wrapping every endpoint served by Start, StartTLS,
+// Serve and ServeTLS, and each request sent over a WebSocket. Middleware runs in registration order, so the first
+// registered sees each request first. Use must be called before serving.
+func (s *A2AServer) Use(mw func(http.Handler) http.Handler) {
+	s.middleware = append(s.middleware, mw)
+}
+
+// withMiddleware wraps h in the middleware added with Use, the first
+// registered outermost
+func (s *A2AServer) withMiddleware(h http.Handler) http.Handler {
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	return h
+}
+
+// Start starts the A2A server, failing without binding if the agent card is invalid
+func (s *A2AServer) Start() error {
+	if err := s.agentCard.Validate(); err != nil {
+		return fmt.Errorf("invalid agent card: %w", err)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// StartTLS starts the A2A server over HTTPS, negotiating HTTP/2 with clients
+// that support it. certFile and keyFile may be empty if the config passed to
+// WithTLSConfig already holds certificates. Like Start, it fails if the agent
+// card is invalid.
+func (s *A2AServer) StartTLS(certFile, keyFile string) error {
+	if err := s.agentCard.Validate(); err != nil {
+		return fmt.Errorf("invalid agent card: %w", err)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	return s.ServeTLS(listener, certFile, keyFile)
+}
+
+// Serve serves the A2A endpoints on the given listener until Stop is called
+func (s *A2AServer) Serve(listener net.Listener) error {
+	return s.serve(func(srv *http.Server) error {
+		return srv.Serve(listener)
+	})
+}
+
+// ServeTLS is like Serve but accepts HTTPS connections, as StartTLS does
+func (s *A2AServer) ServeTLS(listener net.Listener, certFile, keyFile string) error {
+	return s.serve(func(srv *http.Server) error {
+		return srv.ServeTLS(listener, certFile, keyFile)
+	})
+}
+
+// serve runs a new http.Server until Stop is called
+func (s *A2AServer) serve(run func(*http.Server) error) error {
+	if s.schemaErr != nil {
+		return s.schemaErr
+	}
+	srv := s.newHTTPServer()
+
+	s.mu.Lock()
+	s.httpServer = srv
+	s.mu.Unlock()
+
+	if s.taskTTL > 0 {
+		janitorCtx, stopJanitor := context.WithCancel(context.Background())
+		defer stopJanitor()
+		srv.RegisterOnShutdown(stopJanitor)
+		go s.runJanitor(janitorCtx)
+	}
+
+	err := run(srv)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// newHTTPServer builds the http.Server that serves the A2A endpoints. There
+// is deliberately no write timeout: it would cut off streaming responses,
+// which stay open for as long as the task runs.
+func (s *A2AServer) newHTTPServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(agentCardPath, s.handleAgentCard)
+	mux.HandleFunc(healthPath, s.handleHealth)
+	mux.Handle(s.basePath, s)
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics.handler())
+	}
+	if s.websocket {
+		mux.HandleFunc(websocketPath, s.handleWebSocket)
+	}
+
+	var handler http.Handler = mux
+	if s.compression {
+		handler = compress(handler)
+	}
+	handler = s.withMiddleware(handler)
+
+	// Request contexts derive from baseCtx so that Stop can end long-lived
+	// streams. Handlers detached from their request find it under
+	// serverStopKey, so that Stop ends them too.
+	stopCtx, cancel := context.WithCancel(context.Background())
+	baseCtx := context.WithValue(stopCtx, serverStopKey{}, stopCtx)
+	srv := &http.Server{
+		Handler:           handler,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readTimeout,
+		IdleTimeout:       s.idleTimeout,
+		TLSConfig:         s.tlsConfig.Clone(),
+	}
+	srv.RegisterOnShutdown(cancel)
+	return srv
+}
+
+// Stop gracefully shuts down the server. In-flight requests and running task
+// handlers, including those of streams and non-blocking sends, see their
+// context canceled; Stop waits for them to
+// return until ctx expires.
+func (s *A2AServer) Stop(ctx context.Context) error {
+	s.mu.RLock()
+	srv := s.httpServer
+	s.mu.RUnlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// handleAgentCard serves the agent card for discovery
+func (s *A2AServer) handleAgentCard(w http.ResponseWriter, r *http.Request) {
+	if s.handleCORS(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.agentCard)
+}
+
+// handleHealth reports that the server is up, along with the agent version
+func (s *A2AServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.HealthStatus{Status: "ok", Version: s.agentCard.Version})
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *A2AServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.handleCORS(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start := time.Now()
+	r, requestID := withRequestID(w, r)
+	s.logger.Debug("request received", "remote_addr", r.RemoteAddr, "request_id", requestID)
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		s.sendError(w, nil, models.ErrorCodeInvalidRequest, "Content-Type must be application/json")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+
+	var rpc rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&rpc); err != nil {
+		// Bodies that aren't JSON at all are parse errors; well-formed JSON
+		// of the wrong shape, or too much of it, is an invalid request
+		code := models.ErrorCodeParseError
+		message := "Invalid JSON: " + err.Error()
+		var typeErr *json.UnmarshalTypeError
+		var sizeErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &sizeErr):
+			code = models.ErrorCodeInvalidRequest
+			message = fmt.Sprintf("Request body exceeds %d bytes", sizeErr.Limit)
+		case errors.As(err, &typeErr):
+			code = models.ErrorCodeInvalidRequest
+		}
+		s.sendError(w, nil, code, message)
+		return
+	}
+
+	req := rpc.JSONRPCRequest
+
+	// Responses echo the ID exactly as sent, so a numeric ID stays a number
+	id := req.ID
+
+	if req.JSONRPC != "2.0" {
+		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid JSON-RPC version")
+		return
+	}
+	if req.Method == "" {
+		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Missing method")
+		return
+	}
+
+	// A request without an id member is a notification. The method still
+	// runs, but the client gets an empty 204 instead of its result.
+	if !rpc.hasID {
+		defer w.WriteHeader(http.StatusNoContent)
+		w = &notificationWriter{header: make(http.Header)}
+	}
+
+	taskID := taskIDFromParams(req.Params)
+	s.logger.Debug("dispatching request", "method", req.Method, "task_id", taskID, "rpc_id", idToString(id), "request_id", requestID)
+
+	if s.metrics != nil || s.tracer != nil || s.accessLog {
+		var rec *resultRecorder
+		w, rec = recordResult(w)
+		var span trace.Span
+		r, span = s.startSpan(r, req.Method, taskID)
+		defer func() {
+			result := "success"
+			if rec.failed {
+				result = "error"
+				span.SetStatus(codes.Error, "JSON-RPC error response")
+			}
+			s.metrics.observeRequest(req.Method, result)
+			span.End()
+			if s.accessLog {
+				s.logAccess(r, req.Method, taskID, start, rec)
+			}
+		}()
+	}
+
+	switch req.Method {
+	case models.MethodMessageSend:
+		params, err := parseParams[models.TaskSendParams](&req)
+		if err != nil {
+			s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+			return
+		}
+		if err := params.Message.Validate(); err != nil {
+			s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid message: "+err.Error())
+			return
+		}
+		if code, message, ok := s.validateDataParts(params.Message); !ok {
+			s.sendError(w, id, code, message)
+			return
+		}
+		if code, message, ok := s.validateFileURIs(params.Message); !ok {
+			s.sendError(w, id, code, message)
+			return
+		}
+		stampMessage(&params.Message)
+		s.assignTaskID(params)
+		s.handleTaskSend(w, r, *params, id)
+	case models.MethodMessageStream:
+		if !acceptsEventStream(r.Header.Get("Accept")) {
+			s.sendError(w, id, models.ErrorCodeInvalidRequest, "Accept must allow text/event-stream")
+			return
+		}
+		params, err := parseParams[models.TaskSendParams](&req)
+		if err != nil {
+			s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+			return
+		}
+		if err := params.Message.Validate(); err != nil {
+			s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid message: "+err.Error())
+			return
+		}
+		if code, message, ok := s.validateDataParts(params.Message); !ok {
+			s.sendError(w, id, code, message)
+			return
+		}
+		if code, message, ok := s.validateFileURIs(params.Message); !ok {
+			s.sendError(w, id, code, message)
+			return
+		}
+		stampMessage(&params.Message)
+		s.assignTaskID(params)
+		s.handleStreamingTask(w, r, *params, id)
+	case models.MethodTasksGet:
+		s.handleTaskGet(w, r, &req, id)
+	case models.MethodTasksCancel:
+		s.handleTaskCancel(w, &req, id)
+	case models.MethodTasksList:
+		s.handleTaskList(w, &req, id)
+	case models.MethodTasksDelete:
+		s.handleTaskDelete(w, &req, id)
+	case models.MethodTasksPushNotificationSet:
+		s.handleSetPushNotification(w, &req, id)
+	case models.MethodTasksPushNotificationGet:
+		s.handleGetPushNotification(w, &req, id)
+	case models.MethodTasksResubscribe:
+		if !acceptsEventStream(r.Header.Get("Accept")) {
+			s.sendError(w, id, models.ErrorCodeInvalidRequest, "Accept must allow text/event-stream")
+			return
+		}
+		s.handleResubscribe(w, r, &req, id)
+	default:
+		s.sendError(w, id, models.ErrorCodeMethodNotFound, "Method not found")
+	}
+}
+
+// handleTaskSend handles the message/send method
+func (s *A2AServer) handleTaskSend(w http.ResponseWriter, r *http.Request, params models.TaskSendParams, id interface{}) {
+	if !s.acquireSlot() {
+		s.sendError(w, id, models.ErrorCodeInternalError, serverBusyMessage)
+		return
+	}
+	// A detached handler releases the slot itself once it finishes
+	detached := false
+	defer func() {
+		if !detached {
+			s.releaseSlot()
+		}
+	}()
+
+	// Create a new task or continue an existing one
+	s.mu.Lock()
+	if s.idempotentSends {
+		if finished, err := s.finishedTask(params.ID); err != nil || finished != nil {
+			s.mu.Unlock()
+			if err != nil {
+				s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+				return
+			}
+			s.logger.Info("returning finished task for repeated send", "task_id", params.ID)
+			if err := s.attachHistory(finished, params.HistoryLength); err != nil {
+				s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+				return
+			}
+			s.sendResponse(w, id, finished)
+			return
+		}
+	}
+	task, err := s.prepareTask(params)
+	// Non-blocking sends outlive the request, so they can only be stopped
+	// through tasks/cancel or Stop
+	ctx, cancel := r.Context(), context.CancelFunc(nil)
+	if err == nil && !params.Blocking() {
+		ctx, cancel = detachContext(r.Context())
+		s.cancelFuncs[task.ID] = cancel
+	}
+	s.mu.Unlock()
+	if errors.Is(err, errTaskFinished) {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, err.Error())
+		return
+	}
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+
+	// Process task without holding the lock so other tasks can proceed
+	handler := s.handler
+	if h, ok := s.skillHandler(params); ok {
+		handler = h
+	}
+
+	if cancel != nil {
+		// Respond with the working task now and let clients poll tasks/get
+		detached = true
+		result := *task
+		go s.runDetached(ctx, cancel, handler, task, params)
+		if err := s.attachHistory(&result, params.HistoryLength); err != nil {
+			s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+			return
+		}
+		s.sendResponse(w, id, &result)
+		return
+	}
+
+	start := time.Now()
+	updatedTask, err := s.runHandler(ctx, handler, task, &params.Message)
+	s.metrics.observeHandler(models.MethodMessageSend, start)
+	if err != nil {
+		s.logger.Error("task handler failed", "method", models.MethodMessageSend, "task_id", task.ID, "error", err)
+	}
+
+	result, err := s.storeResult(task, updatedTask, err)
+	if err == nil {
+		err = s.attachHistory(result, params.HistoryLength)
+	}
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+
+	// Send response
+	s.sendResponse(w, id, result)
 }
 
-func NewA2AServer(agentCard models.AgentCard, handler func(*models.Task, *models.Message) (*models.Task, error)) *A2AServer {
-	return &A2AServer{
-		agentCard:   agentCard,
-		handler:     handler,
-		taskStore:   make(map[string]*models.Task),
-		taskHistory: make(map[string][]*models.Message),
+// runDetached runs the handler of a non-blocking message/send after the
+// response has been sent, storing the outcome for tasks/get
+func (s *A2AServer) runDetached(ctx context.Context, cancel context.CancelFunc, handler TaskHandler, task *models.Task, params models.TaskSendParams) {
+	defer s.releaseSlot()
+	defer func() {
+		cancel()
+		s.mu.Lock()
+		delete(s.cancelFuncs, task.ID)
+		s.mu.Unlock()
+	}()
+
+	start := time.Now()
+	updatedTask, err := s.runHandler(ctx, handler, task, &params.Message)
+	s.metrics.observeHandler(models.MethodMessageSend, start)
+	if err != nil {
+		s.logger.Error("task handler failed", "method", models.MethodMessageSend, "task_id", task.ID, "error", err)
+	}
+
+	if _, storeErr := s.storeResult(task, updatedTask, err); err == nil && storeErr != nil {
+		s.logger.Error("storing task result failed", "task_id", task.ID, "error", storeErr)
+	}
+}
+
+// runHandler calls a task handler, turning a panic, a timeout or an illegal
+// state transition into an error so that the caller can still fail the task
+// and send a JSON-RPC error
+func (s *A2AServer) runHandler(ctx context.Context, handler TaskHandler, task *models.Task, message *models.Message) (*models.Task, error) {
+	ctx, cancel := s.handlerContext(ctx)
+	defer cancel()
+
+	type result struct {
+		task *models.Task
+		err  error
+	}
+	done := make(chan result, 1)
+
+	// The handler works on its own copy so that a handler still running
+	// after a timeout cannot race with the caller failing the task
+	handlerTask := *task
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.logger.Error("recovered from panic in task handler", "task_id", task.ID, "panic", r, "stack", string(debug.Stack()))
+				done <- result{err: fmt.Errorf("task handler panicked: %v", r)}
+			}
+		}()
+		updated, err := handler(ctx, &handlerTask, message)
+		done <- result{updated, err}
+	}()
+
+	expired := ctx.Done()
+	for {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return nil, r.err
+			}
+			if r.task == nil {
+				s.logger.Error("task handler returned no task", "task_id", task.ID)
+				return nil, errNoTask
+			}
+			if err := r.task.Validate(); err != nil {
+				s.logger.Error("task handler returned invalid task", "task_id", task.ID, "error", err)
+				return nil, fmt.Errorf("task handler returned invalid task: %w", err)
+			}
+			if r.task.Status.State == task.Status.State {
+				return r.task, nil
+			}
+			return r.task, s.checkTransition(task.ID, s.handlerStartState(task.Status.State), r.task.Status.State)
+		case <-expired:
+			if err := s.handlerTimedOut(ctx, task.ID); err != nil {
+				return nil, err
+			}
+			// Canceled for another reason; let the handler wind down
+			expired = nil
+		}
+	}
+}
+
+// errNoTask fails a task whose handler returned neither a task nor an error
+var errNoTask = errors.New("task handler returned no task")
+
+// errHandlerTimeout is the cause of a handler's context ending once the
+// WithHandlerTimeout limit passes
+var errHandlerTimeout = errors.New("task handler timed out")
+
+// serverStopKey is the context key under which request contexts carry a
+// context that ends when the server stops
+type serverStopKey struct{}
+
+// detachContext returns a context for a handler that outlives its request.
+// It keeps the request's values but ends only when cancel is called or the
+// server stops, not when the client goes away.
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop, ok := ctx.Value(serverStopKey{}).(context.Context)
+	if !ok {
+		return detached, cancel
+	}
+	unregister := context.AfterFunc(stop, cancel)
+	return detached, func() {
+		unregister()
+		cancel()
+	}
+}
+
+// handlerContext derives the context a handler runs with, carrying the file
+// URI policy and bounded by the handler timeout when one is set
+func (s *A2AServer) handlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = models.ContextWithURIPolicy(ctx, s.uriPolicy)
+	if s.handlerTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, s.handlerTimeout, errHandlerTimeout)
+}
+
+// handlerTimedOut returns an error, logging the task, if a handler context
+// ended because the handler timeout passed
+func (s *A2AServer) handlerTimedOut(ctx context.Context, taskID string) error {
+	if !errors.Is(context.Cause(ctx), errHandlerTimeout) {
+		return nil
+	}
+	s.logger.Error("task handler timed out", "task_id", taskID, "timeout", s.handlerTimeout)
+	return fmt.Errorf("%w after %s", errHandlerTimeout, s.handlerTimeout)
+}
+
+// failedStatus returns the status of a task whose handler failed with err,
+// explaining timeouts to the client
+func failedStatus(err error) models.TaskStatus {
+	status := newTaskStatus(models.TaskStateFailed)
+	if errors.Is(err, errHandlerTimeout) {
+		message := models.NewTextMessage(models.RoleAgent, err.Error())
+		status.Message = &message
+	}
+	return status
+}
+
+// handlerStartState returns the state a handler's first transition is checked
+// from. Under WithoutImplicitWorking a continued task keeps its stored state,
+// such as input-required, and the handler picking it up stands in for the
+// working step the server skipped.
+func (s *A2AServer) handlerStartState(state models.TaskState) models.TaskState {
+	if s.skipWorking && !state.IsTerminal() {
+		return models.TaskStateWorking
+	}
+	return state
+}
+
+// checkTransition reports an error, logging the offending task, if a handler
+// moved a task between states the A2A state machine does not connect
+func (s *A2AServer) checkTransition(taskID string, from, to models.TaskState) error {
+	if from.CanTransitionTo(to) {
+		return nil
+	}
+	s.logger.Error("task handler made illegal state transition", "task_id", taskID, "from", from, "to", to)
+	return fmt.Errorf("illegal task state transition from %q to %q", from, to)
+}
+
+// storeResult records the outcome of a handler run and returns the task to
+// report. A tasks/cancel request that arrived meanwhile wins over the handler;
+// a handler error marks the task failed. The lock is held only while the
+// store is updated, never while writing the response.
+func (s *A2AServer) storeResult(task, updatedTask *models.Task, handlerErr error) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, _, err := s.taskStore.Get(task.ID)
+	if err == nil && current != nil && current.Status.State == models.TaskStateCanceled {
+		return current, nil
+	}
+
+	if handlerErr != nil {
+		task.Status = failedStatus(handlerErr)
+		if err := s.saveTask(task); err != nil {
+			s.logger.Error("storing failed task failed", "task_id", task.ID, "error", err)
+			return nil, errors.Join(handlerErr, err)
+		}
+		return nil, handlerErr
+	}
+	stampStatus(&updatedTask.Status)
+
+	if err := s.saveTask(updatedTask); err != nil {
+		return nil, err
+	}
+	return updatedTask, nil
+}
+
+// serverBusyMessage is reported when WithMaxConcurrentTasks handlers are already running
+const serverBusyMessage = "Server busy: too many concurrent tasks"
+
+// acquireSlot reserves room for a handler to run, reporting false when the
+// concurrency limit has been reached
+func (s *A2AServer) acquireSlot() bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseSlot frees a slot reserved by acquireSlot
+func (s *A2AServer) releaseSlot() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// prepareTask loads the task being continued, or creates a new one, records
+// the incoming message and request metadata and marks the task working
+// unless WithoutImplicitWorking is set, in which case new tasks are
+// submitted. The returned task carries the full message history so the
+// handler can see earlier turns, e.g. after asking for more input. Callers
+// must hold s.mu.
+func (s *A2AServer) prepareTask(params models.TaskSendParams) (*models.Task, error) {
+	task, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		task = &models.Task{ID: params.ID, SessionID: params.SessionID}
+	} else if task.SessionID == nil {
+		task.SessionID = params.SessionID
+	}
+	// A new message sends the task back to working, or leaves its state to
+	// the handler under WithoutImplicitWorking; finished tasks take neither
+	next := models.TaskStateWorking
+	if s.skipWorking {
+		next = task.Status.State
+	}
+	if exists && (next != task.Status.State || next.IsTerminal()) && !task.Status.State.CanTransitionTo(next) {
+		return nil, fmt.Errorf("%w: task is %s", errTaskFinished, task.Status.State)
+	}
+	task.Metadata = mergeMetadata(task.Metadata, params.Metadata)
+	switch {
+	case !s.skipWorking:
+		task.Status = newTaskStatus(models.TaskStateWorking)
+	case !exists:
+		task.Status = newTaskStatus(models.TaskStateSubmitted)
+	}
+
+	history, err := s.taskStore.History(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Keep history in chronological order even if the clock steps back
+	message := params.Message
+	if message.Timestamp == nil {
+		stampMessage(&message)
+	}
+	if len(history) > 0 {
+		if last := history[len(history)-1].Timestamp; last != nil && message.Timestamp.Before(*last) {
+			message.Timestamp = last
+		}
+	}
+	if err := s.taskStore.AppendHistory(task.ID, &message); err != nil {
+		return nil, err
+	}
+	if err := s.saveTask(task); err != nil {
+		return nil, err
+	}
+	task.History = trimHistory(append(history, &message), nil)
+	return task, nil
+}
+
+// finishedTask returns the stored task with the given ID if it has reached a
+// terminal state, or nil otherwise. The caller must hold s.mu.
+func (s *A2AServer) finishedTask(id string) (*models.Task, error) {
+	task, exists, err := s.taskStore.Get(id)
+	if err != nil || !exists || !task.Status.State.IsTerminal() {
+		return nil, err
+	}
+	return task, nil
+}
+
+// errTaskFinished rejects a message sent to a task in a terminal state,
+// which the A2A state machine never leaves
+var errTaskFinished = errors.New("task has finished and takes no further messages")
+
+// submitTask records a task that does not exist yet in the submitted state,
+// returning that status, or nil if the task already exists. The caller must
+// hold s.mu.
+func (s *A2AServer) submitTask(params models.TaskSendParams) (*models.TaskStatus, error) {
+	_, exists, err := s.taskStore.Get(params.ID)
+	if err != nil || exists {
+		return nil, err
+	}
+	task := &models.Task{
+		ID:        params.ID,
+		SessionID: params.SessionID,
+		Status:    newTaskStatus(models.TaskStateSubmitted),
+		Metadata:  mergeMetadata(nil, params.Metadata),
+	}
+	if err := s.saveTask(task); err != nil {
+		return nil, err
+	}
+	return &task.Status, nil
+}
+
+// saveTask stores a task, recording a state transition when its state changed
+// and notifying the task's push webhook when it reaches a terminal state. The
+// caller must hold s.mu.
+func (s *A2AServer) saveTask(task *models.Task) error {
+	previous, exists, err := s.taskStore.Get(task.ID)
+	if err != nil {
+		return err
+	}
+	changed := !exists || previous.Status.State != task.Status.State
+	if changed && exists && previous.Status.State.IsTerminal() {
+		s.logger.Error("refusing to change finished task", "task_id", task.ID, "from", previous.Status.State, "to", task.Status.State)
+		return fmt.Errorf("illegal task state transition from %q to %q", previous.Status.State, task.Status.State)
+	}
+	if changed {
+		if err := s.taskStore.AppendTransition(task.ID, task.Status); err != nil {
+			return err
+		}
+		var from models.TaskState
+		if exists {
+			from = previous.Status.State
+		}
+		s.logger.Info("task state changed", "task_id", task.ID, "from", from, "to", task.Status.State)
 	}
-}
 
-// Start starts the A2A server
-func (s *A2AServer) Start() error {
-	mux := http.NewServeMux()
-	mux.Handle(s.basePath, s)
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.port), mux)
+	// Messages and transitions have their own tables; storing them with the
+	// task would copy the whole conversation on every turn
+	stored := *task
+	stored.History, stored.StatusHistory = nil, nil
+	if err := s.taskStore.Save(&stored); err != nil {
+		return err
+	}
+	if changed && task.Status.State.IsTerminal() {
+		s.notifyPush(&stored)
+	}
+	return nil
 }
 
-// ServeHTTP implements the http.Handler interface
-func (s *A2AServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleTaskGet handles the tasks/get method. Responses carry an ETag header;
+// a request whose If-None-Match header still matches gets a
+// models.TaskNotModified result instead of the task.
+func (s *A2AServer) handleTaskGet(w http.ResponseWriter, r *http.Request, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.TaskQueryParams](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
 		return
 	}
 
-	var req models.JSONRPCRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		// Return JSON-RPC error response with ErrorCodeInvalidRequest
-		response := models.JSONRPCResponse{
-			JSONRPCMessage: models.JSONRPCMessage{
-				JSONRPC: "2.0",
-			},
-			Error: &models.JSONRPCError{
-				Code:    int(models.ErrorCodeInvalidRequest),
-				Message: "Invalid JSON: " + err.Error(),
-			},
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	task, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if !exists {
+		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
 		return
 	}
 
-	parseTaskSendParams := func(req *models.JSONRPCRequest) (*models.TaskSendParams, error) {
-		var params models.TaskSendParams
-		paramsBytes, err := json.Marshal(req.Params)
-		if err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal(paramsBytes, &params); err != nil {
-			return nil, err
-		}
-		return &params, nil
+	history, err := s.taskStore.History(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
 	}
+	task.History = trimHistory(history, params.HistoryLength)
 
-	switch req.Method {
-	case "message/send":
-		_, err := parseTaskSendParams(&req)
-		if err != nil {
-			s.sendError(w, req.ID.(string), models.ErrorCodeInvalidRequest, "Invalid parameters")
-			return
-		}
-		s.handleTaskSend(w, &req, req.ID.(string))
-	case "message/stream":
-		params, err := parseTaskSendParams(&req)
+	if caps := s.agentCard.Capabilities; caps.StateTransitionHistory != nil && *caps.StateTransitionHistory {
+		task.StatusHistory, err = s.taskStore.Transitions(params.ID)
 		if err != nil {
-			s.sendError(w, req.ID.(string), models.ErrorCodeInvalidRequest, "Invalid parameters")
+			s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
 			return
 		}
-		s.handleStreamingTask(w, r, *params)
-	case "tasks/get":
-		s.handleTaskGet(w, &req, req.ID.(string))
-	case "tasks/cancel":
-		s.handleTaskCancel(w, &req, req.ID.(string))
-	default:
-		s.sendError(w, req.ID.(string), models.ErrorCodeMethodNotFound, "Method not found")
 	}
-}
 
-// handleTaskSend handles the message/send method
-func (s *A2AServer) handleTaskSend(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
-	var params models.TaskSendParams
-	paramsBytes, err := json.Marshal(req.Params)
+	etag, err := taskETag(task)
 	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		s.sendResponse(w, id, models.TaskNotModified{ID: task.ID, NotModified: true})
 		return
 	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+
+	s.sendResponse(w, id, task)
+}
+
+// attachHistory sets a task's history to its most recent historyLength
+// messages, or clears it when historyLength is nil so that responses only
+// carry history on request
+func (s *A2AServer) attachHistory(task *models.Task, historyLength *int) error {
+	if historyLength == nil {
+		task.History = nil
+		return nil
+	}
+	history, err := s.taskStore.History(task.ID)
+	if err != nil {
+		return err
+	}
+	task.History = trimHistory(history, historyLength)
+	return nil
+}
+
+// trimHistory returns the most recent historyLength messages, or all of them when historyLength is nil
+func trimHistory(history []*models.Message, historyLength *int) []models.Message {
+	start := 0
+	if historyLength != nil && *historyLength < len(history) {
+		start = len(history) - max(*historyLength, 0)
+	}
+
+	messages := make([]models.Message, 0, len(history)-start)
+	for _, msg := range history[start:] {
+		messages = append(messages, *msg)
+	}
+	return messages
+}
+
+// handleTaskCancel handles the tasks/cancel method
+func (s *A2AServer) handleTaskCancel(w http.ResponseWriter, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.TaskIDParams](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
 		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Create new task
-	task := &models.Task{
-		ID: params.ID,
-		Status: models.TaskStatus{
-			State: models.TaskStateWorking,
-		},
+	task, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if !exists {
+		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+		return
 	}
 
-	// Process task
-	updatedTask, err := s.handler(task, &params.Message)
-	if err != nil {
+	if !task.Status.State.CanTransitionTo(models.TaskStateCanceled) {
+		s.sendError(w, id, models.ErrorCodeTaskNotCancelable, "Task cannot be canceled")
+		return
+	}
+
+	// Update task status to canceled
+	task.Status = newTaskStatus(models.TaskStateCanceled)
+	task.Metadata = mergeMetadata(task.Metadata, params.Metadata)
+	if err := s.saveTask(task); err != nil {
 		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
 		return
 	}
 
-	// Store task and history
-	s.taskStore[task.ID] = updatedTask
-	s.taskHistory[task.ID] = append(s.taskHistory[task.ID], &params.Message)
+	// Interrupt the handler if the task is still running
+	if cancel, running := s.cancelFuncs[params.ID]; running {
+		cancel()
+	}
 
-	// Send response
-	s.sendResponse(w, id, updatedTask)
+	s.sendResponse(w, id, task)
 }
 
-// handleTaskGet handles the tasks/get method
-func (s *A2AServer) handleTaskGet(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
-	var params models.TaskQueryParams
-	paramsBytes, err := json.Marshal(req.Params)
+// handleTaskDelete handles the tasks/delete method, removing a task together
+// with its history, push notification config and replay buffer. Running
+// tasks must be canceled first, as their handler would otherwise store them
+// again.
+func (s *A2AServer) handleTaskDelete(w http.ResponseWriter, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.TaskIDParams](req)
 	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
-		return
-	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
 		return
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	task, exists := s.taskStore[params.ID]
+	_, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
 	if !exists {
 		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
 		return
 	}
+	if _, running := s.cancelFuncs[params.ID]; running {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Task is still running")
+		return
+	}
 
-	s.sendResponse(w, id, task)
+	if err := s.taskStore.Delete(params.ID); err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if err := s.pushConfigs.DeletePushConfig(params.ID); err != nil {
+		s.logger.Error("deleting push notification config failed", "task_id", params.ID, "error", err)
+	}
+	delete(s.replays, params.ID)
+	s.logger.Info("task deleted", "task_id", params.ID)
+
+	s.sendResponse(w, id, models.TaskIDParams{ID: params.ID})
 }
 
-// handleTaskCancel handles the tasks/cancel method
-func (s *A2AServer) handleTaskCancel(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
-	var params models.TaskIDParams
-	paramsBytes, err := json.Marshal(req.Params)
-	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+// handleSetPushNotification handles the tasks/pushNotification/set method
+func (s *A2AServer) handleSetPushNotification(w http.ResponseWriter, req *models.JSONRPCRequest, id interface{}) {
+	if caps := s.agentCard.Capabilities; caps.PushNotifications == nil || !*caps.PushNotifications {
+		s.sendError(w, id, models.ErrorCodePushNotificationNotSupported, models.ErrorCodePushNotificationNotSupported.Message())
 		return
 	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+
+	params, err := parseParams[models.TaskPushNotificationConfig](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
 		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task, exists := s.taskStore[params.ID]
+	_, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
 	if !exists {
 		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
 		return
 	}
 
-	// Update task status to canceled
-	task.Status.State = models.TaskStateCanceled
-	s.taskStore[params.ID] = task
+	if err := s.pushConfigs.SavePushConfig(params.ID, params.PushNotificationConfig); err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
 
-	s.sendResponse(w, id, task)
+	s.sendResponse(w, id, params.PushNotificationConfig)
+}
+
+// handleGetPushNotification handles the tasks/pushNotification/get method
+func (s *A2AServer) handleGetPushNotification(w http.ResponseWriter, req *models.JSONRPCRequest, id interface{}) {
+	params, err := parseParams[models.TaskIDParams](req)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Invalid parameters")
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists, err := s.taskStore.Get(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if !exists {
+		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+		return
+	}
+
+	config, exists, err := s.pushConfigs.PushConfig(params.ID)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if !exists {
+		s.sendError(w, id, models.ErrorCodeInternalError, "Push notification config not found")
+		return
+	}
+
+	s.sendResponse(w, id, config)
 }
 
 // sendResponse sends a JSON-RPC response
-func (s *A2AServer) sendResponse(w http.ResponseWriter, id string, result interface{}) {
+func (s *A2AServer) sendResponse(w http.ResponseWriter, id interface{}, result interface{}) {
 	response := models.JSONRPCResponse{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
@@ -204,13 +1252,28 @@ func (s *A2AServer) sendResponse(w http.ResponseWriter, id string, result interf
 		},
 		Result: result,
 	}
+	if err := response.Validate(); err != nil {
+		s.logger.Error("refusing to send invalid response", "error", err)
+		s.sendError(w, id, models.ErrorCodeInternalError, "Internal error: no result")
+		return
+	}
+	// Encode before writing so that a result that cannot be encoded still
+	// gets an error response rather than an empty body
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.logger.Error("encoding response failed", "error", err)
+		s.sendError(w, id, models.ErrorCodeInternalError, "Internal error: result could not be encoded")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.Write(append(data, '\n'))
 }
 
-// sendError sends a JSON-RPC error response
-func (s *A2AServer) sendError(w http.ResponseWriter, id string, code models.ErrorCode, message string) {
+// sendError sends a JSON-RPC error response. id is nil for requests whose ID
+// could not be read, such as malformed bodies.
+func (s *A2AServer) sendError(w http.ResponseWriter, id interface{}, code models.ErrorCode, message string) {
+	markFailed(w, code)
 	response := models.JSONRPCResponse{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
@@ -225,114 +1288,454 @@ func (s *A2AServer) sendError(w http.ResponseWriter, id string, code models.Erro
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("writing error response failed", "error", err)
+	}
 }
 
-func (s *A2AServer) handleStreamingTask(w http.ResponseWriter, r *http.Request, params models.TaskSendParams) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+func (s *A2AServer) handleStreamingTask(w http.ResponseWriter, r *http.Request, params models.TaskSendParams, id interface{}) {
+	if !s.acquireSlot() {
+		s.sendError(w, id, models.ErrorCodeInternalError, serverBusyMessage)
+		return
+	}
 
-	// Check if response writer supports flushing
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+	// Finished tasks are rejected before the stream starts, while a plain
+	// JSON-RPC error can still be sent
+	s.mu.Lock()
+	finished, err := s.finishedTask(params.ID)
+	s.mu.Unlock()
+	if err != nil {
+		s.releaseSlot()
+		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		return
+	}
+	if finished != nil {
+		s.releaseSlot()
+		s.sendError(w, id, models.ErrorCodeInvalidParams, fmt.Sprintf("%v: task is %s", errTaskFinished, finished.Status.State))
 		return
 	}
 
-	// Create a channel to receive task updates
-	updates := make(chan any)
+	flusher := s.startSSE(w)
 
-	// Create a done channel to signal when the goroutine is finished
-	done := make(chan struct{})
+	ctx := r.Context()
+	log := s.logger.With("method", models.MethodMessageStream, "task_id", params.ID)
+
+	// Updates are published on a bus so that resubscribed clients see them
+	// too. This client's subscription is buffered so that the handler is not
+	// held back by every write to a slow client.
+	s.mu.Lock()
+	bus := &eventBus{replay: s.replayBuffer(params.ID)}
+	s.mu.Unlock()
+	sub := bus.subscribe(s.streamBufferSize, ctx.Done())
+	send := bus.publish
 
 	// Start task processing in a goroutine
 	go func() {
-		defer func() {
-			close(updates) // Close updates channel when goroutine exits
-			close(done)    // Signal that goroutine is done
-		}()
+		defer s.releaseSlot()
+		defer bus.close() // End the stream however the goroutine exits
 
 		// Recover from any panics to ensure channels are closed
 		defer func() {
 			if r := recover(); r != nil {
-				// Log the panic (you might want to use a proper logger)
-				fmt.Printf("Recovered from panic in streaming task: %v\n", r)
+				log.Error("recovered from panic in streaming task", "panic", r)
 			}
 		}()
 
+		// The handler keeps running if this client goes away, for
+		// resubscribers to follow; only tasks/cancel or Stop end it
+		taskCtx, cancel := detachContext(ctx)
+		defer cancel()
+
 		s.mu.Lock()
-		// Create new task
-		task := &models.Task{
-			ID: params.ID,
-			Status: models.TaskStatus{
-				State: models.TaskStateWorking,
-			},
+		// New tasks pass through submitted so that clients see the whole
+		// lifecycle; continued tasks go straight back to working
+		submitted, saveErr := s.submitTask(params)
+		var task *models.Task
+		if saveErr == nil {
+			task, saveErr = s.prepareTask(params)
+		}
+		if saveErr == nil {
+			s.cancelFuncs[task.ID] = cancel
+			s.streams[task.ID] = bus
 		}
-		s.taskStore[task.ID] = task
-		s.taskHistory[task.ID] = append(s.taskHistory[task.ID], &params.Message)
 		s.mu.Unlock()
 
-		// Send initial status update
-		updates <- models.TaskStatusUpdateEvent{
-			ID:     task.ID,
-			Status: task.Status,
-			Final:  boolPtr(false),
+		if saveErr != nil {
+			send(models.TaskStatusUpdateEvent{
+				ID:     params.ID,
+				Status: newTaskStatus(models.TaskStateFailed),
+				Final:  boolPtr(true),
+			})
+			return
 		}
 
-		// Process task using the handler field
-		updatedTask, err := s.handler(task, &params.Message)
-		if err != nil {
-			// Send error status update
-			updates <- models.TaskStatusUpdateEvent{
-				ID: task.ID,
-				Status: models.TaskStatus{
-					State: models.TaskStateFailed,
-				},
-				Final: boolPtr(true),
-			}
-			return
+		defer s.finishStream(task.ID, bus)
+
+		// Send initial status updates. Resubscribers may still be listening
+		// if this client has already gone away.
+		if submitted != nil {
+			send(models.TaskStatusUpdateEvent{
+				ID:     task.ID,
+				Status: *submitted,
+				Final:  boolPtr(false),
+			})
+		}
+		if !s.skipWorking {
+			send(models.TaskStatusUpdateEvent{
+				ID:     task.ID,
+				Status: task.Status,
+				Final:  boolPtr(false),
+			})
+		}
+
+		// Process task using the streaming handler when registered
+		var updatedTask *models.Task
+		var err error
+		start := time.Now()
+		if h, ok := s.skillHandler(params); ok {
+			updatedTask, err = s.runHandler(taskCtx, h, task, &params.Message)
+		} else if s.streamingHandler != nil {
+			updatedTask, err = s.runStreamingHandler(taskCtx, task, &params.Message, send)
+		} else {
+			updatedTask, err = s.runHandler(taskCtx, s.handler, task, &params.Message)
 		}
+		s.metrics.observeHandler(models.MethodMessageStream, start)
 
-		// Update task in store
+		// A tasks/cancel request wins over whatever the handler returned
 		s.mu.Lock()
-		s.taskStore[task.ID] = updatedTask
+		current, _, _ := s.taskStore.Get(task.ID)
+		canceled := current != nil && current.Status.State == models.TaskStateCanceled
+		if !canceled {
+			if err == nil {
+				// Update task in store
+				stampStatus(&updatedTask.Status)
+				err = s.saveTask(updatedTask)
+			}
+			if err != nil && current != nil {
+				current.Status = failedStatus(err)
+				if saveErr := s.saveTask(current); saveErr != nil {
+					log.Error("storing failed task failed", "error", saveErr)
+				}
+			}
+		}
 		s.mu.Unlock()
 
+		if canceled {
+			send(models.TaskStatusUpdateEvent{
+				ID:     task.ID,
+				Status: current.Status,
+				Final:  boolPtr(true),
+			})
+			return
+		}
+
+		if err != nil {
+			log.Error("task handler failed", "error", err)
+			// Send error status update
+			send(models.TaskStatusUpdateEvent{
+				ID:     task.ID,
+				Status: failedStatus(err),
+				Final:  boolPtr(true),
+			})
+			return
+		}
+
 		// Send final status update
-		updates <- models.TaskStatusUpdateEvent{
+		send(models.TaskStatusUpdateEvent{
 			ID:     updatedTask.ID,
 			Status: updatedTask.Status,
 			Final:  boolPtr(true),
-		}
+		})
 	}()
 
-	// Stream updates to the client
-	encoder := json.NewEncoder(w)
+	s.pumpSSE(ctx, w, flusher, sub.events, log)
+	bus.unsubscribe(sub)
+}
+
+// startSSE sets the Server-Sent Events headers and returns the flusher that
+// pushes each event to the client
+func (s *A2AServer) startSSE(w http.ResponseWriter) http.Flusher {
+	// Set headers for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	if s.corsOrigins == nil {
+		// Without WithCORS, keep streams open to any origin as before
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		return flusher
+	}
+	// Middleware may wrap the writer without exposing Flush. Reach the
+	// underlying writer through Unwrap where possible; otherwise events are
+	// buffered and reach the client together when the stream ends.
+	s.logger.Debug("response writer cannot flush, streaming through ResponseController")
+	return controllerFlusher{http.NewResponseController(w)}
+}
+
+// controllerFlusher flushes through an http.ResponseController, doing nothing
+// when no writer in the chain supports flushing
+type controllerFlusher struct {
+	rc *http.ResponseController
+}
+
+func (f controllerFlusher) Flush() {
+	f.rc.Flush()
+}
+
+// pumpSSE writes updates to the client until the channel closes or the client disconnects
+func (s *A2AServer) pumpSSE(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, updates <-chan streamEvent, log *slog.Logger) {
+	defer s.metrics.streamStarted()()
+
+	// Keepalive comments are sent only after a full interval without
+	// events. A nil channel never fires, leaving heartbeats off.
+	var heartbeat <-chan time.Time
+	resetHeartbeat := func() {}
+	if s.heartbeatInterval > 0 {
+		ticker := time.NewTicker(s.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+		resetHeartbeat = func() { ticker.Reset(s.heartbeatInterval) }
+	}
+
+	// With flush coalescing, written events wait for the window timer or
+	// for enough of them to pile up. A nil channel never fires.
+	var flushTimer *time.Timer
+	var flushDue <-chan time.Time
+	pending := 0
+	flush := func() {
+		flusher.Flush()
+		pending = 0
+		if flushTimer != nil {
+			flushTimer.Stop()
+			flushDue = nil
+		}
+	}
+
 	for {
 		select {
+		case <-heartbeat:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				log.Info("client disconnected", "error", err)
+				return
+			}
+			flush()
+		case <-flushDue:
+			flushDue = nil
+			flush()
 		case update, ok := <-updates:
 			if !ok {
 				// Channel closed, we're done
+				if pending > 0 {
+					flush()
+				}
 				return
 			}
 			resp := models.SendTaskStreamingResponse{
-				Result: update,
+				Result: update.event,
 				Error:  nil,
 			}
 
-			if err := encoder.Encode(resp); err != nil {
+			err := writeSSEEvent(w, update.id, resp)
+			if errors.Is(err, errEncodeEvent) {
+				// Tell the client instead of leaving a gap in the stream
+				log.Error("encoding stream event failed", "error", err)
+				err = writeSSEEvent(w, update.id, models.SendTaskStreamingResponse{
+					Error: &models.A2AError{
+						JSONRPCError: models.JSONRPCError{Message: "Internal error: event could not be encoded"},
+						Code:         models.ErrorCodeInternalError,
+					},
+				})
+			}
+			if err != nil {
+				log.Info("client disconnected", "error", err)
 				return
 			}
-			flusher.Flush()
-		case <-r.Context().Done():
+			resetHeartbeat()
+			pending++
+			switch {
+			case s.flushWindow <= 0, isFinalEvent(update.event), s.flushMaxEvents > 0 && pending >= s.flushMaxEvents:
+				flush()
+			case flushDue == nil:
+				if flushTimer == nil {
+					flushTimer = time.NewTimer(s.flushWindow)
+					defer flushTimer.Stop()
+				} else {
+					flushTimer.Reset(s.flushWindow)
+				}
+				flushDue = flushTimer.C
+			}
+		case <-ctx.Done():
 			// Client disconnected
+			log.Info("client disconnected")
 			return
-		case <-done:
-			// Goroutine finished
-			return
 		}
 	}
 }
+
+// isFinalEvent reports whether a stream update is a status event marked final
+func isFinalEvent(update any) bool {
+	e, ok := update.(models.TaskStatusUpdateEvent)
+	return ok && e.Final != nil && *e.Final
+}
+
+// errEncodeEvent marks a stream event that could not be encoded, as opposed
+// to one that could not be written to the client
+var errEncodeEvent = errors.New("encoding stream event")
+
+// writeSSEEvent writes v as a single Server-Sent Events frame, with an id
+// field unless id is zero
+func writeSSEEvent(w io.Writer, id uint64, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errEncodeEvent, err)
+	}
+	if id != 0 {
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", id, data)
+	} else {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	return err
+}
+
+// runStreamingHandler runs the streaming handler, forwarding its events to the
+// client and applying them to the task. The final status event is left to the
+// caller so exactly one is sent.
+func (s *A2AServer) runStreamingHandler(ctx context.Context, task *models.Task, message *models.Message, send func(any)) (*models.Task, error) {
+	ctx, cancel := s.handlerContext(ctx)
+	defer cancel()
+
+	events := make(chan any)
+	errCh := make(chan error, 1)
+
+	// As in runHandler, the handler works on its own copy so that changes it
+	// makes to the task cannot race with the server storing it
+	handlerTask := *task
+	go func() {
+		defer close(events)
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("streaming handler panicked: %v", r)
+			}
+		}()
+		errCh <- s.streamingHandler(ctx, &handlerTask, message, events)
+	}()
+
+	updated := *task
+	from := s.handlerStartState(task.Status.State)
+	var eventErr error
+	expired := ctx.Done()
+stream:
+	for {
+		var event any
+		select {
+		case e, ok := <-events:
+			if !ok {
+				break stream
+			}
+			event = e
+		case <-expired:
+			if err := s.handlerTimedOut(ctx, task.ID); err != nil {
+				// Drain whatever the handler still sends so it can exit
+				go func() {
+					for range events {
+					}
+				}()
+				return nil, err
+			}
+			expired = nil
+			continue
+		}
+
+		if eventErr != nil {
+			// Keep draining so the handler is never blocked
+			continue
+		}
+		switch e := event.(type) {
+		case models.TaskStatusUpdateEvent:
+			if e.Status.Message != nil {
+				if err := e.Status.Message.Validate(); err != nil {
+					s.logger.Error("streaming handler sent invalid status message", "task_id", task.ID, "error", err)
+					eventErr = fmt.Errorf("streaming handler sent invalid status message: %w", err)
+					continue
+				}
+			}
+			if e.Status.State != updated.Status.State {
+				if eventErr = s.checkTransition(task.ID, from, e.Status.State); eventErr != nil {
+					continue
+				}
+				from = e.Status.State
+			}
+			stampStatus(&e.Status)
+			updated.Status = e.Status
+			if e.Final != nil && *e.Final {
+				continue
+			}
+			event = e
+			s.saveUnlessCanceled(&updated)
+		case models.TaskArtifactUpdateEvent:
+			if err := e.Artifact.Validate(); err != nil {
+				s.logger.Error("streaming handler sent invalid artifact", "task_id", task.ID, "error", err)
+				eventErr = fmt.Errorf("streaming handler sent invalid artifact: %w", err)
+				continue
+			}
+			updated.Artifacts = mergeArtifact(updated.Artifacts, e.Artifact)
+			s.saveUnlessCanceled(&updated)
+		}
+
+		send(event)
+	}
+
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	if eventErr != nil {
+		return nil, eventErr
+	}
+
+	// A handler that never reported an outcome is assumed to have completed
+	if updated.Status.State == models.TaskStateWorking || updated.Status.State == models.TaskStateSubmitted {
+		updated.Status.State = models.TaskStateCompleted
+	}
+	return &updated, nil
+}
+
+// saveUnlessCanceled stores an intermediate task update unless the task was canceled meanwhile
+func (s *A2AServer) saveUnlessCanceled(task *models.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists, err := s.taskStore.Get(task.ID)
+	if err != nil || (exists && current.Status.State == models.TaskStateCanceled) {
+		return
+	}
+	s.saveTask(task)
+}
+
+// mergeArtifact applies an artifact chunk to a task's artifacts. A chunk with
+// Append set extends the parts of the artifact sharing its Index; a chunk
+// without Append replaces it. Chunks for unseen indexes are added.
+func mergeArtifact(artifacts []models.Artifact, chunk models.Artifact) []models.Artifact {
+	merged := make([]models.Artifact, len(artifacts))
+	copy(merged, artifacts)
+
+	if chunk.Index != nil {
+		for i := range merged {
+			if merged[i].Index == nil || *merged[i].Index != *chunk.Index {
+				continue
+			}
+			if chunk.Append != nil && *chunk.Append {
+				parts := make([]models.Part, 0, len(merged[i].Parts)+len(chunk.Parts))
+				parts = append(parts, merged[i].Parts...)
+				merged[i].Parts = append(parts, chunk.Parts...)
+				merged[i].LastChunk = chunk.LastChunk
+			} else {
+				merged[i] = chunk
+			}
+			return merged
+		}
+	}
+
+	return append(merged, chunk)
+}
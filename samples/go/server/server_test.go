@@ -2,24 +2,40 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"a2a/models"
 )
 
 // mockTaskHandler is a simple task handler for testing
-func mockTaskHandler(task *models.Task, message *models.Message) (*models.Task, error) {
+func mockTaskHandler(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
 	task.Status.State = models.TaskStateCompleted
 	return task, nil
 }
 
+// mockInputRequiredTaskHandler is a task handler that leaves the task awaiting input
+func mockInputRequiredTaskHandler(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+	task.Status.State = models.TaskStateInputRequired
+	return task, nil
+}
+
 // mockErrorTaskHandler is a task handler that returns an error for testing
-func mockErrorTaskHandler(task *models.Task, message *models.Message) (*models.Task, error) {
+func mockErrorTaskHandler(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
 	return nil, fmt.Errorf("test error")
 }
 
@@ -71,7 +87,7 @@ func TestA2AServer_HandleTaskSend(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -137,7 +153,7 @@ func TestA2AServer_HandleTaskGet(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -215,7 +231,7 @@ func TestA2AServer_HandleTaskGet(t *testing.T) {
 }
 
 func TestA2AServer_HandleTaskCancel(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	server := NewA2AServer(mockAgentCard, mockInputRequiredTaskHandler)
 	server.port = 8080
 	server.basePath = "/"
 
@@ -223,7 +239,7 @@ func TestA2AServer_HandleTaskCancel(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -327,8 +343,133 @@ func TestErrorResponse(t *testing.T) {
 		t.Error("Expected error, got nil")
 	}
 
-	if response.Error.Code != int(models.ErrorCodeInvalidRequest) {
-		t.Errorf("Expected error code %d, got %d", models.ErrorCodeInvalidRequest, response.Error.Code)
+	if response.Error.Code != int(models.ErrorCodeParseError) {
+		t.Errorf("Expected error code %d, got %d", models.ErrorCodeParseError, response.Error.Code)
+	}
+}
+
+func TestMalformedRequests(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	tests := []struct {
+		name string
+		body string
+		code models.ErrorCode
+	}{
+		{"not json", `{"jsonrpc":`, models.ErrorCodeParseError},
+		{"not an object", `[1, 2]`, models.ErrorCodeInvalidRequest},
+		{"missing version", `{"id": 1, "method": "tasks/get", "params": {"id": "t"}}`, models.ErrorCodeInvalidRequest},
+		{"wrong version", `{"jsonrpc": "1.0", "id": 1, "method": "tasks/get", "params": {"id": "t"}}`, models.ErrorCodeInvalidRequest},
+		{"missing method", `{"jsonrpc": "2.0", "id": 1, "params": {"id": "t"}}`, models.ErrorCodeInvalidRequest},
+		{"wrong method type", `{"jsonrpc": "2.0", "id": 1, "method": 7}`, models.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			var response models.JSONRPCResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Error == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if response.Error.Code != int(tt.code) {
+				t.Errorf("Expected error code %d, got %d", tt.code, response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestContentTypeValidation(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	getBody := `{"jsonrpc": "2.0", "id": 1, "method": "tasks/get", "params": {"id": "t"}}`
+	streamBody := `{"jsonrpc": "2.0", "id": 1, "method": "message/stream", "params": {"id": "t", "message": {"role": "user", "parts": [{"type": "text", "text": "Hello"}]}}}`
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		accept      string
+		code        models.ErrorCode
+	}{
+		{"form data", "id=t", "application/x-www-form-urlencoded", "", models.ErrorCodeInvalidRequest},
+		{"plain text", getBody, "text/plain", "", models.ErrorCodeInvalidRequest},
+		{"malformed content type", getBody, "application/", "", models.ErrorCodeInvalidRequest},
+		{"json with charset", getBody, "application/json; charset=utf-8", "", models.ErrorCodeTaskNotFound},
+		{"no content type", getBody, "", "", models.ErrorCodeTaskNotFound},
+		{"stream accepting json only", streamBody, "application/json", "application/json", models.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			var response models.JSONRPCResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Error == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if response.Error.Code != int(tt.code) {
+				t.Errorf("Expected error code %d, got %d: %s", tt.code, response.Error.Code, response.Error.Message)
+			}
+		})
+	}
+}
+
+func TestAcceptsEventStream(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{"text/event-stream", true},
+		{"application/json, text/event-stream;q=0.9", true},
+		{"*/*", true},
+		{"text/*", true},
+		{"application/json", false},
+		{"text/html", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsEventStream(tt.header); got != tt.want {
+			t.Errorf("acceptsEventStream(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithMaxBodyBytes(1024))
+
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr(strings.Repeat("a", 2048))}}},
+	}
+	response := doJSONRPC(t, server, models.MethodMessageSend, params)
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) {
+		t.Fatalf("Expected invalid request error, got %+v", response.Error)
+	}
+
+	// Requests under the limit are unaffected
+	params.Message.Parts[0].Text = stringPtr("Hello")
+	if response := doJSONRPC(t, server, models.MethodMessageSend, params); response.Error != nil {
+		t.Errorf("Unexpected error: %v", response.Error)
 	}
 }
 
@@ -341,7 +482,7 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -382,10 +523,10 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 	}
 
 	// Parse the streaming response
-	// The response should contain multiple JSON objects, one per line
-	responseLines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
-	if len(responseLines) < 2 {
-		t.Errorf("Expected at least 2 response lines, got %d", len(responseLines))
+	// The response should contain multiple SSE frames, one JSON object each
+	responseLines := sseData(t, w.Body.String())
+	if len(responseLines) < 3 {
+		t.Fatalf("Expected at least 3 response lines, got %d", len(responseLines))
 	}
 
 	// Check the initial status update
@@ -413,14 +554,28 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 		t.Errorf("Expected task ID %s, got %s", "test-task-1", initialEvent.ID)
 	}
 
-	if initialEvent.Status.State != models.TaskStateWorking {
-		t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, initialEvent.Status.State)
+	if initialEvent.Status.State != models.TaskStateSubmitted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateSubmitted, initialEvent.Status.State)
 	}
 
 	if initialEvent.Final == nil || *initialEvent.Final {
 		t.Error("Expected Final to be false for initial update")
 	}
 
+	// The task moves to working once the handler starts
+	var workingResponse models.SendTaskStreamingResponse
+	if err := json.Unmarshal([]byte(responseLines[1]), &workingResponse); err != nil {
+		t.Fatalf("Failed to unmarshal working response: %v", err)
+	}
+	workingResultBytes, _ := json.Marshal(workingResponse.Result)
+	var workingEvent models.TaskStatusUpdateEvent
+	if err := json.Unmarshal(workingResultBytes, &workingEvent); err != nil {
+		t.Fatalf("Failed to unmarshal working event: %v", err)
+	}
+	if workingEvent.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, workingEvent.Status.State)
+	}
+
 	// Check the final status update
 	var finalResponse models.SendTaskStreamingResponse
 	if err := json.Unmarshal([]byte(responseLines[len(responseLines)-1]), &finalResponse); err != nil {
@@ -464,7 +619,7 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -499,10 +654,10 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 	}
 
 	// Parse the streaming response
-	// The response should contain multiple JSON objects, one per line
-	responseLines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
-	if len(responseLines) < 2 {
-		t.Errorf("Expected at least 2 response lines, got %d", len(responseLines))
+	// The response should contain multiple SSE frames, one JSON object each
+	responseLines := sseData(t, w.Body.String())
+	if len(responseLines) < 3 {
+		t.Fatalf("Expected at least 3 response lines, got %d", len(responseLines))
 	}
 
 	// Check the initial status update
@@ -530,14 +685,28 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 		t.Errorf("Expected task ID %s, got %s", "test-task-1", initialEvent.ID)
 	}
 
-	if initialEvent.Status.State != models.TaskStateWorking {
-		t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, initialEvent.Status.State)
+	if initialEvent.Status.State != models.TaskStateSubmitted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateSubmitted, initialEvent.Status.State)
 	}
 
 	if initialEvent.Final == nil || *initialEvent.Final {
 		t.Error("Expected Final to be false for initial update")
 	}
 
+	// The task moves to working once the handler starts
+	var workingResponse models.SendTaskStreamingResponse
+	if err := json.Unmarshal([]byte(responseLines[1]), &workingResponse); err != nil {
+		t.Fatalf("Failed to unmarshal working response: %v", err)
+	}
+	workingResultBytes, _ := json.Marshal(workingResponse.Result)
+	var workingEvent models.TaskStatusUpdateEvent
+	if err := json.Unmarshal(workingResultBytes, &workingEvent); err != nil {
+		t.Fatalf("Failed to unmarshal working event: %v", err)
+	}
+	if workingEvent.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, workingEvent.Status.State)
+	}
+
 	// Check the error status update
 	var finalResponse models.SendTaskStreamingResponse
 	if err := json.Unmarshal([]byte(responseLines[len(responseLines)-1]), &finalResponse); err != nil {
@@ -581,7 +750,7 @@ func TestA2AServer_HandleStreamingTaskNoFlusher(t *testing.T) {
 	params := models.TaskSendParams{
 		ID: "test-task-1",
 		Message: models.Message{
-			Role: "user",
+			Role: models.RoleUser,
 			Parts: []models.Part{
 				{Text: stringPtr("Hello")},
 			},
@@ -608,13 +777,2662 @@ func TestA2AServer_HandleStreamingTaskNoFlusher(t *testing.T) {
 
 	server.ServeHTTP(w, req)
 
-	if w.statusCode != http.StatusInternalServerError {
-		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.statusCode)
+	// Events are buffered and still delivered, rather than failing the request
+	if w.statusCode != 0 && w.statusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.statusCode)
+	}
+	if ct := w.header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	results := streamResults(t, w.body.String())
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 events, got %d: %s", len(results), w.body.String())
+	}
+	var finalEvent models.TaskStatusUpdateEvent
+	if err := json.Unmarshal(results[2], &finalEvent); err != nil {
+		t.Fatalf("Failed to unmarshal final event: %v", err)
+	}
+	if finalEvent.Status.State != models.TaskStateCompleted || finalEvent.Final == nil || !*finalEvent.Final {
+		t.Errorf("Expected final completed event, got %+v", finalEvent)
+	}
+}
+
+// unwrappingResponseWriter hides Flush the way some middleware does, while
+// exposing the writer it wraps through Unwrap
+type unwrappingResponseWriter struct {
+	*mockNonFlushingResponseWriter
+	rec *httptest.ResponseRecorder
+}
+
+func (w *unwrappingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.rec
+}
+
+func TestA2AServer_StreamingFlushesThroughUnwrap(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	rec := httptest.NewRecorder()
+	w := &unwrappingResponseWriter{&mockNonFlushingResponseWriter{header: make(http.Header)}, rec}
+
+	server.ServeHTTP(w, req)
+
+	if !rec.Flushed {
+		t.Error("Expected events to be flushed through the unwrapped writer")
+	}
+	if results := streamResults(t, w.body.String()); len(results) != 3 {
+		t.Errorf("Expected 3 events, got %d", len(results))
+	}
+}
+
+func TestA2AServer_NonStringRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "numeric id",
+			body: `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"Hello"}]}}}`,
+		},
+		{
+			name: "null id",
+			body: `{"jsonrpc":"2.0","id":null,"method":"tasks/get","params":{"id":"missing"}}`,
+		},
+		{
+			name: "numeric id unknown method",
+			body: `{"jsonrpc":"2.0","id":42,"method":"unknown/method"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response models.JSONRPCResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.JSONRPC != "2.0" {
+				t.Errorf("Expected jsonrpc 2.0, got %q", response.JSONRPC)
+			}
+		})
+	}
+}
+
+func TestA2AServer_Notification(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "missing id",
+			body: `{"jsonrpc":"2.0","method":"tasks/cancel","params":{"id":"test-task"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemoryTaskStore()
+			store.Save(&models.Task{ID: "test-task", Status: models.TaskStatus{State: models.TaskStateWorking}})
+			server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("Expected an empty response, got %q", w.Body.String())
+			}
+
+			// The notification is still processed
+			task, _, _ := store.Get("test-task")
+			if task.Status.State != models.TaskStateCanceled {
+				t.Errorf("Expected the task to be canceled, got %s", task.Status.State)
+			}
+		})
+	}
+}
+
+func TestA2AServer_EchoesRequestIDType(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"numeric id", `{"jsonrpc":"2.0","id":7,"method":"tasks/get","params":{"id":"missing"}}`, `7`},
+		{"string id", `{"jsonrpc":"2.0","id":"7","method":"tasks/get","params":{"id":"missing"}}`, `"7"`},
+		{"numeric id on success", `{"jsonrpc":"2.0","id":8,"method":"message/send","params":{"id":"t","message":{"role":"user","parts":[{"text":"Hello"}]}}}`, `8`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			var response struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if string(response.ID) != tt.want {
+				t.Errorf("Expected id %s, got %s", tt.want, response.ID)
+			}
+		})
+	}
+}
+
+func TestIDToString(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{"abc", "abc"},
+		{float64(1), "1"},
+		{float64(1.5), "1.5"},
+		{json.Number("42"), "42"},
+	}
+
+	for _, tt := range tests {
+		if got := idToString(tt.in); got != tt.want {
+			t.Errorf("idToString(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	// Params decoded from a request body are generic JSON values
+	var good models.JSONRPCRequest
+	json.Unmarshal([]byte(`{"params":{"id":"test-task","historyLength":2}}`), &good)
+
+	params, err := parseParams[models.TaskQueryParams](&good)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if params.ID != "test-task" || params.HistoryLength == nil || *params.HistoryLength != 2 {
+		t.Errorf("Expected params to be decoded, got %+v", params)
+	}
+
+	malformed := []struct {
+		name   string
+		params interface{}
+	}{
+		{"wrong field type", map[string]interface{}{"id": 42}},
+		{"not an object", "test-task"},
+		{"unencodable", func() {}},
+	}
+	for _, tt := range malformed {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseParams[models.TaskQueryParams](&models.JSONRPCRequest{Params: tt.params}); err == nil {
+				t.Error("Expected error for malformed params")
+			}
+		})
+	}
+}
+
+func TestA2AServer_StreamingHandlerOutlivesClient(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	handlerDone := make(chan error, 1)
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(handlerStarted)
+		<-ctx.Done()
+		handlerDone <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID: "test-task-1",
+			Message: models.Message{
+				Role:  models.RoleUser,
+				Parts: []models.Part{{Text: stringPtr("Hello")}},
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	served := make(chan struct{})
+	go func() {
+		server.ServeHTTP(httptest.NewRecorder(), req)
+		close(served)
+	}()
+
+	// Simulate the client going away once the handler is running. The
+	// stream ends but the task keeps running for resubscribers.
+	<-handlerStarted
+	cancel()
+	<-served
+
+	select {
+	case err := <-handlerDone:
+		t.Fatalf("Expected handler to outlive the client, it ended with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// tasks/cancel still stops it
+	response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	select {
+	case err := <-handlerDone:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handler did not observe the cancellation")
+	}
+}
+
+func TestA2AServer_CancelRunningStreamingTask(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(handlerStarted)
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+		// A misbehaving handler still reports completion
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID: "test-task-1",
+			Message: models.Message{
+				Role:  models.RoleUser,
+				Parts: []models.Part{{Text: stringPtr("Hello")}},
+			},
+		},
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		server.ServeHTTP(w, req)
+		close(served)
+	}()
+
+	<-handlerStarted
+	response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("Streaming task was not interrupted by cancel")
+	}
+
+	responseLines := sseData(t, w.Body.String())
+	var finalResponse models.SendTaskStreamingResponse
+	if err := json.Unmarshal([]byte(responseLines[len(responseLines)-1]), &finalResponse); err != nil {
+		t.Fatalf("Failed to unmarshal final response: %v", err)
+	}
+
+	var finalEvent models.TaskStatusUpdateEvent
+	resultBytes, _ := json.Marshal(finalResponse.Result)
+	if err := json.Unmarshal(resultBytes, &finalEvent); err != nil {
+		t.Fatalf("Failed to unmarshal final event: %v", err)
+	}
+	if finalEvent.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected final event state %s, got %s", models.TaskStateCanceled, finalEvent.Status.State)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCanceled, task.Status.State)
+	}
+}
+
+func TestA2AServer_HandleTaskGetHistoryLength(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockInputRequiredTaskHandler)
+
+	for _, text := range []string{"one", "two", "three"} {
+		doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID: "test-task-1",
+			Message: models.Message{
+				Role:  models.RoleUser,
+				Parts: []models.Part{{Text: stringPtr(text)}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name          string
+		historyLength *int
+		want          []string
+	}{
+		{"nil returns everything", nil, []string{"one", "two", "three"}},
+		{"zero returns nothing", intPtr(0), []string{}},
+		{"truncates to most recent", intPtr(2), []string{"two", "three"}},
+		{"larger than stored returns everything", intPtr(10), []string{"one", "two", "three"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+				TaskIDParams:  models.TaskIDParams{ID: "test-task-1"},
+				HistoryLength: tt.historyLength,
+			})
+			if response.Error != nil {
+				t.Fatalf("Expected no error, got %v", response.Error)
+			}
+
+			// The history key is always present, as [] when nothing is asked for
+			if history, ok := response.Result.(map[string]interface{})["history"].([]interface{}); !ok || len(history) != len(tt.want) {
+				t.Fatalf("Expected a history array of %d entries, got %v", len(tt.want), response.Result)
+			}
+
+			var task models.Task
+			decodeResult(t, response, &task)
+
+			if len(task.History) != len(tt.want) {
+				t.Fatalf("Expected %d history entries, got %d", len(tt.want), len(task.History))
+			}
+			for i, want := range tt.want {
+				if got := *task.History[i].Parts[0].Text; got != want {
+					t.Errorf("Expected history[%d] %q, got %q", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestA2AServer_HandleTaskSendHistoryLength(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Book a flight"),
+	})
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:            "test-task-1",
+		Message:       models.NewTextMessage(models.RoleUser, "To Paris"),
+		HistoryLength: intPtr(1),
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var task models.Task
+	decodeResult(t, response, &task)
+	if len(task.History) != 1 || task.History[0].Text() != "To Paris" {
+		t.Errorf("Expected only the most recent message, got %+v", task.History)
+	}
+}
+
+func TestA2AServer_HistoryNotStoredWithTask(t *testing.T) {
+	var seen int
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		seen = len(task.History)
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+
+	var response models.JSONRPCResponse
+	for _, text := range []string{"one", "two", "three"} {
+		response = doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, text),
+		})
+	}
+
+	if seen != 3 {
+		t.Errorf("Expected the handler to see 3 messages, got %d", seen)
+	}
+	if _, ok := response.Result.(map[string]interface{})["history"]; ok {
+		t.Errorf("Expected no history in the response without historyLength, got %v", response.Result)
+	}
+	if task, _, _ := store.Get("test-task"); len(task.History) != 0 {
+		t.Errorf("Expected the stored task to leave history to the history table, got %d messages", len(task.History))
+	}
+}
+
+func TestA2AServer_HistoryTimestamps(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if message.Timestamp == nil {
+			t.Error("Expected the handler to see when the message was received")
+		}
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+
+	// A message stored by a server whose clock ran ahead
+	future := time.Now().UTC().Add(time.Hour)
+	store.Save(&models.Task{ID: "test-task", Status: models.TaskStatus{State: models.TaskStateInputRequired}})
+	store.AppendHistory("test-task", &models.Message{Role: models.RoleUser, Timestamp: &future})
+
+	for _, text := range []string{"one", "two", "three"} {
+		doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, text),
+		})
+	}
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+
+	if len(task.History) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(task.History))
+	}
+	for i, msg := range task.History {
+		if msg.Timestamp == nil {
+			t.Fatalf("Expected message %d to have a timestamp", i)
+		}
+		if i > 0 && msg.Timestamp.Before(*task.History[i-1].Timestamp) {
+			t.Errorf("Expected non-decreasing timestamps, message %d at %v precedes %v", i, msg.Timestamp, task.History[i-1].Timestamp)
+		}
+	}
+}
+
+func TestA2AServer_TaskMetadata(t *testing.T) {
+	var seen map[string]interface{}
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		seen = task.Metadata
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:       "test-task",
+		Message:  models.NewTextMessage(models.RoleUser, "Hello"),
+		Metadata: map[string]interface{}{"region": "eu", "priority": "low"},
+	})
+	if seen["region"] != "eu" {
+		t.Errorf("Expected the handler to see the request metadata, got %v", seen)
+	}
+
+	// Later turns add to the metadata, overriding earlier keys
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:       "test-task",
+		Message:  models.NewTextMessage(models.RoleUser, "Hurry"),
+		Metadata: map[string]interface{}{"priority": "high"},
+	})
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+
+	want := map[string]interface{}{"region": "eu", "priority": "high"}
+	if !reflect.DeepEqual(task.Metadata, want) {
+		t.Errorf("Expected metadata %v, got %v", want, task.Metadata)
+	}
+}
+
+func TestA2AServer_TaskArtifacts(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		task.Artifacts = append(task.Artifacts, models.Artifact{
+			Name:  stringPtr("answer"),
+			Parts: []models.Part{{Text: stringPtr("42")}},
+		})
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	var sent models.Task
+	decodeResult(t, response, &sent)
+	if len(sent.Artifacts) != 1 {
+		t.Fatalf("Expected 1 artifact in send response, got %d", len(sent.Artifacts))
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+
+	var task models.Task
+	decodeResult(t, response, &task)
+	if len(task.Artifacts) != 1 {
+		t.Fatalf("Expected 1 artifact, got %d", len(task.Artifacts))
+	}
+	if *task.Artifacts[0].Name != "answer" || *task.Artifacts[0].Parts[0].Text != "42" {
+		t.Errorf("Unexpected artifact %+v", task.Artifacts[0])
+	}
+}
+
+func TestA2AServer_InvalidParams(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, "not an object")
+	if response.Error == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected error code %d, got %d", models.ErrorCodeInvalidParams, response.Error.Code)
+	}
+}
+
+func TestA2AServer_HandleTaskCancelStates(t *testing.T) {
+	tests := []struct {
+		state      models.TaskState
+		cancelable bool
+	}{
+		{models.TaskStateSubmitted, true},
+		{models.TaskStateWorking, true},
+		{models.TaskStateInputRequired, true},
+		{models.TaskStateCompleted, false},
+		{models.TaskStateCanceled, false},
+		{models.TaskStateFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			store := NewInMemoryTaskStore()
+			store.Save(&models.Task{ID: "test-task-1", Status: models.TaskStatus{State: tt.state}})
+			server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+
+			response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task-1"})
+
+			task, _, _ := store.Get("test-task-1")
+			if tt.cancelable {
+				if response.Error != nil {
+					t.Fatalf("Expected no error, got %v", response.Error)
+				}
+				if task.Status.State != models.TaskStateCanceled {
+					t.Errorf("Expected task state %s, got %s", models.TaskStateCanceled, task.Status.State)
+				}
+				return
+			}
+
+			if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotCancelable) {
+				t.Fatalf("Expected task not cancelable error, got %v", response.Error)
+			}
+			if task.Status.State != tt.state {
+				t.Errorf("Expected task state to remain %s, got %s", tt.state, task.Status.State)
+			}
+		})
+	}
+}
+
+func TestA2AServer_StreamingHandler(t *testing.T) {
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{
+			ID: task.ID,
+			Artifact: models.Artifact{
+				Parts: []models.Part{{Text: stringPtr("Hello, ")}},
+			},
+		}
+		events <- models.TaskArtifactUpdateEvent{
+			ID: task.ID,
+			Artifact: models.Artifact{
+				Parts: []models.Part{{Text: stringPtr("world")}},
+			},
+		}
+		events <- models.TaskStatusUpdateEvent{
+			ID:     task.ID,
+			Status: models.TaskStatus{State: models.TaskStateCompleted},
+			Final:  boolPtr(true),
+		}
+		return nil
+	}
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler, WithStreamingHandler(streamingHandler))
+
+	w := doStream(t, server, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	results := streamResults(t, w.Body.String())
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 events, got %d: %s", len(results), w.Body.String())
 	}
 
-	if w.body.String() != "Streaming not supported\n" {
-		t.Errorf("Expected error message 'Streaming not supported', got '%s'", w.body.String())
+	for i, raw := range results[2:4] {
+		var event models.TaskArtifactUpdateEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			t.Fatalf("Failed to unmarshal artifact event: %v", err)
+		}
+		if len(event.Artifact.Parts) != 1 {
+			t.Errorf("Expected artifact event %d to carry one part, got %s", i, raw)
+		}
 	}
+
+	var finalEvent models.TaskStatusUpdateEvent
+	if err := json.Unmarshal(results[4], &finalEvent); err != nil {
+		t.Fatalf("Failed to unmarshal final event: %v", err)
+	}
+	if finalEvent.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, finalEvent.Status.State)
+	}
+	if finalEvent.Final == nil || !*finalEvent.Final {
+		t.Error("Expected Final to be true for final update")
+	}
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if len(task.Artifacts) != 2 {
+		t.Errorf("Expected 2 stored artifacts, got %d", len(task.Artifacts))
+	}
+}
+
+func TestA2AServer_StreamingArtifactChunks(t *testing.T) {
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{
+			ID: task.ID,
+			Artifact: models.Artifact{
+				Index: intPtr(0),
+				Parts: []models.Part{{Text: stringPtr("Hello, ")}},
+			},
+		}
+		events <- models.TaskArtifactUpdateEvent{
+			ID: task.ID,
+			Artifact: models.Artifact{
+				Index:     intPtr(0),
+				Append:    boolPtr(true),
+				LastChunk: boolPtr(true),
+				Parts:     []models.Part{{Text: stringPtr("world")}},
+			},
+		}
+		return nil
+	}
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler, WithStreamingHandler(streamingHandler))
+
+	w := doStream(t, server, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	var artifactEvents []models.TaskArtifactUpdateEvent
+	for _, raw := range streamResults(t, w.Body.String()) {
+		var probe map[string]json.RawMessage
+		json.Unmarshal(raw, &probe)
+		if _, ok := probe["artifact"]; !ok {
+			continue
+		}
+		var event models.TaskArtifactUpdateEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			t.Fatalf("Failed to unmarshal artifact event: %v", err)
+		}
+		artifactEvents = append(artifactEvents, event)
+	}
+
+	if len(artifactEvents) != 2 {
+		t.Fatalf("Expected 2 artifact events on the wire, got %d", len(artifactEvents))
+	}
+	if artifactEvents[1].Artifact.LastChunk == nil || !*artifactEvents[1].Artifact.LastChunk {
+		t.Error("Expected second chunk to carry LastChunk")
+	}
+
+	// The stored task holds the reassembled artifact
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if len(task.Artifacts) != 1 {
+		t.Fatalf("Expected 1 stored artifact, got %d", len(task.Artifacts))
+	}
+	if parts := task.Artifacts[0].Parts; len(parts) != 2 || *parts[0].Text != "Hello, " || *parts[1].Text != "world" {
+		t.Errorf("Expected appended parts, got %+v", parts)
+	}
+}
+
+func TestMergeArtifact(t *testing.T) {
+	base := []models.Artifact{{Index: intPtr(0), Parts: []models.Part{{Text: stringPtr("a")}}}}
+
+	appended := mergeArtifact(base, models.Artifact{Index: intPtr(0), Append: boolPtr(true), Parts: []models.Part{{Text: stringPtr("b")}}})
+	if len(appended) != 1 || len(appended[0].Parts) != 2 {
+		t.Errorf("Expected append to extend parts, got %+v", appended)
+	}
+	if len(base[0].Parts) != 1 {
+		t.Error("Expected merge not to modify the input slice")
+	}
+
+	replaced := mergeArtifact(base, models.Artifact{Index: intPtr(0), Parts: []models.Part{{Text: stringPtr("c")}}})
+	if len(replaced) != 1 || *replaced[0].Parts[0].Text != "c" {
+		t.Errorf("Expected chunk without append to replace, got %+v", replaced)
+	}
+
+	added := mergeArtifact(base, models.Artifact{Index: intPtr(1), Parts: []models.Part{{Text: stringPtr("d")}}})
+	if len(added) != 2 {
+		t.Errorf("Expected new index to add an artifact, got %+v", added)
+	}
+}
+
+func TestWriteSSEEvent(t *testing.T) {
+	var buf bytes.Buffer
+	event := models.TaskStatusUpdateEvent{
+		ID:     "test-task-1",
+		Status: models.TaskStatus{State: models.TaskStateWorking},
+		Final:  boolPtr(false),
+	}
+
+	if err := writeSSEEvent(&buf, 0, event); err != nil {
+		t.Fatalf("Failed to write event: %v", err)
+	}
+
+	want := "data: {\"id\":\"test-task-1\",\"status\":{\"state\":\"working\"},\"final\":false}\n\n"
+	if buf.String() != want {
+		t.Errorf("Expected frame %q, got %q", want, buf.String())
+	}
+
+	// Numbered events carry their ID for clients to resume from
+	buf.Reset()
+	if err := writeSSEEvent(&buf, 7, event); err != nil {
+		t.Fatalf("Failed to write event: %v", err)
+	}
+	if want := "id: 7\n" + want; buf.String() != want {
+		t.Errorf("Expected frame %q, got %q", want, buf.String())
+	}
+}
+
+func TestUnencodableResults(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	// A text part without text cannot be encoded
+	invalid := models.Part{Type: models.PartTypeText}
+
+	w := httptest.NewRecorder()
+	server.sendResponse(w, "1", invalid)
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected a JSON-RPC response, got %q: %v", w.Body.String(), err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected internal error, got %+v", response)
+	}
+
+	// Streams report the event as an error and carry on
+	updates := make(chan streamEvent, 2)
+	updates <- streamEvent{id: 1, event: models.TaskArtifactUpdateEvent{ID: "t", Artifact: models.Artifact{Parts: []models.Part{invalid}}}}
+	updates <- streamEvent{id: 2, event: models.TaskStatusUpdateEvent{ID: "t", Status: models.TaskStatus{State: models.TaskStateCompleted}, Final: boolPtr(true)}}
+	close(updates)
+	w = httptest.NewRecorder()
+	server.pumpSSE(context.Background(), w, w, updates, server.logger)
+
+	frames := sseData(t, w.Body.String())
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %q", w.Body.String())
+	}
+	var first models.SendTaskStreamingResponse
+	json.Unmarshal([]byte(frames[0]), &first)
+	if first.Error == nil || first.Error.Code != models.ErrorCodeInternalError {
+		t.Errorf("Expected internal error frame, got %s", frames[0])
+	}
+}
+
+func TestHandlerReturnsInvalidArtifact(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Artifacts = []models.Artifact{{Parts: []models.Part{{Type: models.PartTypeText}}}}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		events <- models.TaskArtifactUpdateEvent{ID: task.ID, Artifact: models.Artifact{Parts: []models.Part{{Type: models.PartTypeText}}}}
+		return nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store), WithStreamingHandler(streamingHandler))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "send-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected internal error for message/send, got %+v", response.Error)
+	}
+	if task, _, _ := store.Get("send-task"); task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected message/send task to fail, got %s", task.Status.State)
+	}
+
+	w := doStream(t, server, models.TaskSendParams{
+		ID:      "stream-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	results := streamResults(t, w.Body.String())
+	var final models.TaskStatusUpdateEvent
+	json.Unmarshal(results[len(results)-1], &final)
+	if final.Status.State != models.TaskStateFailed || final.Final == nil || !*final.Final {
+		t.Errorf("Expected final failed event for message/stream, got %+v", final)
+	}
+	if task, _, _ := store.Get("stream-task"); len(task.Artifacts) != 0 {
+		t.Errorf("Expected the invalid artifact not to be stored, got %+v", task.Artifacts)
+	}
+}
+
+func TestA2AServer_Stop(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	handlerDone := make(chan error, 1)
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if message.Parts[0].Text != nil && *message.Parts[0].Text == "block" {
+			close(handlerStarted)
+			<-ctx.Done()
+			handlerDone <- ctx.Err()
+			return nil, ctx.Err()
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := "http://" + listener.Addr().String()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	// Avoid pooled connections, which Shutdown only reaps after a delay
+	httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	post := func(method, text string) (*http.Response, error) {
+		reqBody, _ := json.Marshal(models.JSONRPCRequest{
+			JSONRPCMessage: models.JSONRPCMessage{
+				JSONRPC: "2.0",
+				JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+					ID: "1",
+				},
+			},
+			Method: method,
+			Params: models.TaskSendParams{
+				ID: text,
+				Message: models.Message{
+					Role:  models.RoleUser,
+					Parts: []models.Part{{Text: stringPtr(text)}},
+				},
+			},
+		})
+		return httpClient.Post(addr, "application/json", bytes.NewBuffer(reqBody))
+	}
+
+	resp, err := post(models.MethodMessageSend, "hello")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Start a long-running stream that only ends when its context is canceled
+	streamResp, err := post(models.MethodMessageStream, "block")
+	if err != nil {
+		t.Fatalf("Failed to start stream: %v", err)
+	}
+	defer streamResp.Body.Close()
+	<-handlerStarted
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+
+	select {
+	case err := <-handlerDone:
+		if err != context.Canceled {
+			t.Errorf("Expected streaming handler to see context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Streaming handler context was not canceled")
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("Expected Serve to return nil after Stop, got %v", err)
+	}
+
+	if _, err := post(models.MethodMessageSend, "hello"); err == nil {
+		t.Error("Expected request to fail after Stop")
+	}
+}
+
+func TestA2AServer_MultiTurnInputRequired(t *testing.T) {
+	var seenHistory [][]models.Message
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		seenHistory = append(seenHistory, task.History)
+		if len(task.History) < 2 {
+			task.Status.State = models.TaskStateInputRequired
+			return task, nil
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	turns := []struct {
+		text string
+		want models.TaskState
+	}{
+		{"Book a flight", models.TaskStateInputRequired},
+		{"To Paris", models.TaskStateCompleted},
+	}
+
+	for _, turn := range turns {
+		response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID: "test-task-1",
+			Message: models.Message{
+				Role:  models.RoleUser,
+				Parts: []models.Part{{Text: stringPtr(turn.text)}},
+			},
+		})
+		if response.Error != nil {
+			t.Fatalf("Expected no error, got %v", response.Error)
+		}
+
+		var task models.Task
+		decodeResult(t, response, &task)
+		if task.Status.State != turn.want {
+			t.Errorf("Expected task state %s after %q, got %s", turn.want, turn.text, task.Status.State)
+		}
+	}
+
+	if len(seenHistory) != 2 {
+		t.Fatalf("Expected handler to run twice, ran %d times", len(seenHistory))
+	}
+	if len(seenHistory[1]) != 2 || *seenHistory[1][0].Parts[0].Text != "Book a flight" || *seenHistory[1][1].Parts[0].Text != "To Paris" {
+		t.Errorf("Expected second turn to see both messages, got %+v", seenHistory[1])
+	}
+}
+
+func TestA2AServer_StatusTimestamps(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		task.Status.Message = &models.Message{
+			Role:  models.RoleAgent,
+			Parts: []models.Part{{Text: stringPtr("Done")}},
+		}
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	w := doStream(t, server, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	results := streamResults(t, w.Body.String())
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(results))
+	}
+
+	var submitted, working, completed models.TaskStatusUpdateEvent
+	json.Unmarshal(results[0], &submitted)
+	json.Unmarshal(results[1], &working)
+	json.Unmarshal(results[2], &completed)
+
+	if submitted.Status.Timestamp == nil || working.Status.Timestamp == nil || completed.Status.Timestamp == nil {
+		t.Fatalf("Expected timestamps on every status, got %v, %v and %v", submitted.Status.Timestamp, working.Status.Timestamp, completed.Status.Timestamp)
+	}
+	if working.Status.Timestamp.Before(*submitted.Status.Timestamp) {
+		t.Errorf("Expected working timestamp %v not to precede submitted timestamp %v", working.Status.Timestamp, submitted.Status.Timestamp)
+	}
+	if completed.Status.Timestamp.Before(*working.Status.Timestamp) {
+		t.Errorf("Expected completed timestamp %v not to precede working timestamp %v", completed.Status.Timestamp, working.Status.Timestamp)
+	}
+	if completed.Status.Message == nil || *completed.Status.Message.Parts[0].Text != "Done" {
+		t.Errorf("Expected status message from handler, got %+v", completed.Status.Message)
+	}
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.Timestamp == nil || !task.Status.Timestamp.Equal(*completed.Status.Timestamp) {
+		t.Errorf("Expected stored timestamp %v, got %v", completed.Status.Timestamp, task.Status.Timestamp)
+	}
+}
+
+func TestA2AServer_StateTransitionHistory(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	response := doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+
+	if len(task.StatusHistory) != 2 {
+		t.Fatalf("Expected 2 transitions, got %d", len(task.StatusHistory))
+	}
+	if task.StatusHistory[0].State != models.TaskStateWorking || task.StatusHistory[1].State != models.TaskStateCompleted {
+		t.Errorf("Expected working then completed, got %+v", task.StatusHistory)
+	}
+	for i, status := range task.StatusHistory {
+		if status.Timestamp == nil {
+			t.Errorf("Expected transition %d to carry a timestamp", i)
+		}
+	}
+
+	// Agents that don't advertise the capability omit the history
+	card := mockAgentCard
+	card.Capabilities.StateTransitionHistory = boolPtr(false)
+	server = NewA2AServer(card, mockTaskHandler)
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "test-task-1"},
+	})
+	task = models.Task{}
+	decodeResult(t, response, &task)
+	if len(task.StatusHistory) != 0 {
+		t.Errorf("Expected no transitions without the capability, got %+v", task.StatusHistory)
+	}
+}
+
+func TestA2AServer_PushNotification(t *testing.T) {
+	card := mockAgentCard
+	card.Capabilities.PushNotifications = boolPtr(true)
+	server := NewA2AServer(card, mockTaskHandler)
+
+	// Unknown tasks are rejected
+	response := doJSONRPC(t, server, models.MethodTasksPushNotificationGet, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Fatalf("Expected task not found error, got %v", response.Error)
+	}
+
+	// Create the task
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	config := models.PushNotificationConfig{
+		URL:   "https://example.com/notify",
+		Token: stringPtr("secret"),
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksPushNotificationSet, models.TaskPushNotificationConfig{
+		ID:                     "test-task-1",
+		PushNotificationConfig: config,
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var setResult models.PushNotificationConfig
+	decodeResult(t, response, &setResult)
+	if setResult.URL != config.URL {
+		t.Errorf("Expected URL %s, got %s", config.URL, setResult.URL)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksPushNotificationGet, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var getResult models.PushNotificationConfig
+	decodeResult(t, response, &getResult)
+	if getResult.URL != config.URL {
+		t.Errorf("Expected URL %s, got %s", config.URL, getResult.URL)
+	}
+	if getResult.Token == nil || *getResult.Token != "secret" {
+		t.Errorf("Expected token secret, got %v", getResult.Token)
+	}
+}
+
+func TestPushNotificationNotSupported(t *testing.T) {
+	// mockAgentCard advertises no push notification support
+	server := NewA2AServer(mockAgentCard, mockInputRequiredTaskHandler)
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+
+	response := doJSONRPC(t, server, models.MethodTasksPushNotificationSet, models.TaskPushNotificationConfig{
+		ID:                     "test-task-1",
+		PushNotificationConfig: models.PushNotificationConfig{URL: "https://example.com/notify"},
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodePushNotificationNotSupported) {
+		t.Fatalf("Expected push notification not supported error, got %+v", response.Error)
+	}
+
+	// Nothing was stored
+	response = doJSONRPC(t, server, models.MethodTasksPushNotificationGet, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error == nil {
+		t.Errorf("Expected no config to be stored, got %+v", response.Result)
+	}
+}
+
+func TestPushNotificationOnCancel(t *testing.T) {
+	type notification struct {
+		task  models.Task
+		token string
+	}
+	received := make(chan notification, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var task models.Task
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		received <- notification{task: task, token: r.Header.Get(pushTokenHeader)}
+	}))
+	defer webhook.Close()
+
+	card := mockAgentCard
+	card.Capabilities.PushNotifications = boolPtr(true)
+	server := NewA2AServer(card, mockInputRequiredTaskHandler)
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	doJSONRPC(t, server, models.MethodTasksPushNotificationSet, models.TaskPushNotificationConfig{
+		ID:                     "test-task-1",
+		PushNotificationConfig: models.PushNotificationConfig{URL: webhook.URL, Token: stringPtr("secret")},
+	})
+
+	response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	select {
+	case n := <-received:
+		if n.task.ID != "test-task-1" || n.task.Status.State != models.TaskStateCanceled {
+			t.Errorf("Expected canceled task test-task-1, got %s in state %s", n.task.ID, n.task.Status.State)
+		}
+		if n.token != "secret" {
+			t.Errorf("Expected notification token secret, got %q", n.token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected webhook to be notified of the cancellation")
+	}
+}
+
+func TestPushNotificationOnCompletion(t *testing.T) {
+	received := make(chan models.Task, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var task models.Task
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		received <- task
+	}))
+	defer webhook.Close()
+
+	// The first turn asks for input, the second completes the task
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if *message.Parts[0].Text == "Hello" {
+			task.Status.State = models.TaskStateInputRequired
+		} else {
+			task.Status.State = models.TaskStateCompleted
+		}
+		return task, nil
+	}
+	card := mockAgentCard
+	card.Capabilities.PushNotifications = boolPtr(true)
+	server := NewA2AServer(card, handler)
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	doJSONRPC(t, server, models.MethodTasksPushNotificationSet, models.TaskPushNotificationConfig{
+		ID:                     "test-task-1",
+		PushNotificationConfig: models.PushNotificationConfig{URL: webhook.URL},
+	})
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Here you go"),
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	select {
+	case task := <-received:
+		if task.ID != "test-task-1" || task.Status.State != models.TaskStateCompleted {
+			t.Errorf("Expected completed task test-task-1, got %s in state %s", task.ID, task.Status.State)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected webhook to be notified of the completion")
+	}
+
+	// Only the terminal transition is pushed
+	select {
+	case task := <-received:
+		t.Errorf("Expected a single notification, also got state %s", task.Status.State)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMaxConcurrentTasks(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blockingHandler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if task.ID == "blocking" {
+			close(started)
+			<-release
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, blockingHandler, WithMaxConcurrentTasks(1))
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	done := make(chan models.JSONRPCResponse)
+	go func() {
+		done <- doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "blocking", Message: message})
+	}()
+	<-started
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "second", Message: message})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Fatalf("Expected busy error, got %+v", response.Error)
+	}
+
+	// The rejected request must not have created a task
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "second"}})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected task not found, got %+v", response.Error)
+	}
+
+	close(release)
+	if response := <-done; response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+
+	// Once the slot is free, new tasks run again
+	response = doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "third", Message: message})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+}
+
+func TestTaskGetWhileHandlerRunning(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blockingHandler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if task.ID == "slow" {
+			close(started)
+			<-release
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, blockingHandler)
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	if response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "fast", Message: message}); response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+
+	done := make(chan models.JSONRPCResponse)
+	go func() {
+		done <- doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "slow", Message: message})
+	}()
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	// Neither an unrelated task nor the running one may be blocked by the slow handler
+	for _, id := range []string{"fast", "slow"} {
+		result := make(chan models.JSONRPCResponse, 1)
+		go func() {
+			result <- doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: id}})
+		}()
+
+		select {
+		case response := <-result:
+			if response.Error != nil {
+				t.Errorf("Unexpected error for %s: %v", id, response.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("tasks/get for %s blocked while another handler was running", id)
+		}
+	}
+}
+
+func TestTaskCancelWhileHandlerRunning(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blockingHandler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		close(started)
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, blockingHandler)
+
+	message := models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}}
+	done := make(chan models.JSONRPCResponse)
+	go func() {
+		done <- doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{ID: "test-task", Message: message})
+	}()
+	<-started
+
+	response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task"})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+	close(release)
+
+	// The cancellation wins over the result the handler returns afterwards
+	response = <-done
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCanceled, task.Status.State)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler, WithLogger(logger))
+
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	}
+	doJSONRPC(t, server, models.MethodMessageSend, params)
+
+	entries := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		entries[entry["msg"].(string)] = entry
+	}
+
+	for _, msg := range []string{"request received", "dispatching request", "task handler failed", "task state changed"} {
+		if _, ok := entries[msg]; !ok {
+			t.Errorf("Expected %q to be logged, got %s", msg, buf.String())
+		}
+	}
+	if entry := entries["dispatching request"]; entry != nil {
+		if entry["method"] != models.MethodMessageSend || entry["task_id"] != "test-task" {
+			t.Errorf("Expected method and task ID on dispatch, got %v", entry)
+		}
+	}
+	if entry := entries["task handler failed"]; entry != nil {
+		if entry["task_id"] != "test-task" || entry["error"] == nil {
+			t.Errorf("Expected task ID and error on handler failure, got %v", entry)
+		}
+	}
+}
+
+func TestHandleTaskSendPanic(t *testing.T) {
+	panickingHandler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		panic("boom")
+	}
+	server := NewA2AServer(mockAgentCard, panickingHandler)
+
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	}
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: models.MethodMessageSend,
+		Params: params,
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Fatalf("Expected internal error, got %+v", response.Error)
+	}
+	if response.ID != "1" {
+		t.Errorf("Expected ID 1, got %v", response.ID)
+	}
+
+	// The task is marked failed rather than left working
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+}
+
+func TestIllegalStateTransition(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Status.State = models.TaskStateSubmitted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Fatalf("Expected internal error, got %+v", response.Error)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		<-ctx.Done()
+		close(canceled)
+		time.Sleep(time.Second)
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithHandlerTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the request to end at the timeout, took %s", elapsed)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Fatalf("Expected internal error, got %+v", response.Error)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler's context to be canceled")
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+	if task.Status.Message == nil || !strings.Contains(task.Status.Message.Text(), "timed out") {
+		t.Errorf("Expected a timeout message, got %+v", task.Status.Message)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithMetrics())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := "http://" + listener.Addr().String()
+	go server.Serve(listener)
+	defer server.Stop(context.Background())
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: models.MethodTasksGet,
+		Params: models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}},
+	})
+	// Avoid pooled connections, which Shutdown only reaps after a delay
+	httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := httpClient.Post(addr+"/", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = httpClient.Get(addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	expected := `a2a_requests_total{method="tasks/get",result="error"} 1`
+	if !strings.Contains(string(body), expected) {
+		t.Errorf("Expected metrics to contain %q, got:\n%s", expected, body)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	srv := NewA2AServer(mockAgentCard, mockTaskHandler).newHTTPServer()
+	if srv.ReadTimeout != 30*time.Second {
+		t.Errorf("Expected default read timeout 30s, got %v", srv.ReadTimeout)
+	}
+	if srv.IdleTimeout != 120*time.Second {
+		t.Errorf("Expected default idle timeout 120s, got %v", srv.IdleTimeout)
+	}
+	if srv.WriteTimeout != 0 {
+		t.Errorf("Expected no write timeout so streams stay open, got %v", srv.WriteTimeout)
+	}
+
+	srv = NewA2AServer(mockAgentCard, mockTaskHandler,
+		WithReadTimeout(5*time.Second),
+		WithIdleTimeout(time.Minute),
+	).newHTTPServer()
+	if srv.ReadTimeout != 5*time.Second || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("Expected read timeouts 5s, got %v and %v", srv.ReadTimeout, srv.ReadHeaderTimeout)
+	}
+	if srv.IdleTimeout != time.Minute {
+		t.Errorf("Expected idle timeout 1m, got %v", srv.IdleTimeout)
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	// Borrow httptest's self-signed certificate
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := certServer.TLS.Certificates[0]
+	roots := x509.NewCertPool()
+	roots.AddCert(certServer.Certificate())
+	certServer.Close()
+
+	server := NewA2AServer(mockAgentCard, mockTaskHandler,
+		WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+	)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := "https://" + listener.Addr().String()
+	go server.ServeTLS(listener, "", "")
+	defer server.Stop(context.Background())
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}}
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		},
+	})
+	req, _ := http.NewRequest("POST", addr+"/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+
+	// Streaming still flushes each event under TLS
+	body, _ := io.ReadAll(resp.Body)
+	results := streamResults(t, string(body))
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(results))
+	}
+	var final models.TaskStatusUpdateEvent
+	if err := json.Unmarshal(results[2], &final); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if final.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected final state %s, got %s", models.TaskStateCompleted, final.Status.State)
+	}
+}
+
+func TestUseMiddleware(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	var order []string
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	server.Use(tag("first"))
+	server.Use(tag("second"))
+
+	req := httptest.NewRequest("GET", "/.well-known/agent.json", nil)
+	w := httptest.NewRecorder()
+	server.newHTTPServer().Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Values("X-Middleware"); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("Expected middleware headers [first second], got %v", got)
+	}
+	if len(order) != 2 || order[0] != "first" {
+		t.Errorf("Expected middleware to run in registration order, got %v", order)
+	}
+}
+
+func TestStartInvalidAgentCard(t *testing.T) {
+	server := NewA2AServer(models.AgentCard{Name: "Test Agent"}, mockTaskHandler, WithPort(0))
+
+	err := server.Start()
+	if err == nil || !strings.Contains(err.Error(), "invalid agent card") {
+		t.Errorf("Expected invalid agent card error, got %v", err)
+	}
+}
+
+func TestStreamingHeartbeat(t *testing.T) {
+	slowHandler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		time.Sleep(100 * time.Millisecond)
+		task.Status = newTaskStatus(models.TaskStateCompleted)
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, slowHandler, WithHeartbeatInterval(10*time.Millisecond))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	keepalive := strings.Index(body, ": keepalive\n\n")
+	if keepalive == -1 {
+		t.Fatalf("Expected a keepalive comment, got %q", body)
+	}
+	if final := strings.LastIndex(body, "data: "); keepalive > final {
+		t.Errorf("Expected keepalive before the final event, got %q", body)
+	}
+
+	// Events are still delivered intact around the comments
+	var frames []string
+	for _, frame := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		if !strings.HasPrefix(frame, ":") {
+			frames = append(frames, frame)
+		}
+	}
+	if events := sseData(t, strings.Join(frames, "\n\n")+"\n\n"); len(events) != 3 {
+		t.Errorf("Expected 3 events, got %d", len(events))
+	}
+}
+
+// blockingWriter holds back every write until release is closed, standing in
+// for a client that reads slowly
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestStreamBufferSlowClient(t *testing.T) {
+	const chunks = 8
+	handlerDone := make(chan struct{})
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		defer close(handlerDone)
+		for i := 0; i < chunks; i++ {
+			events <- models.TaskArtifactUpdateEvent{
+				ID:       task.ID,
+				Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr(fmt.Sprintf("chunk %d", i))}}},
+			}
+		}
+		return nil
+	}
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler,
+		WithStreamingHandler(streamingHandler), WithStreamBufferSize(chunks+2))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{})}
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		server.ServeHTTP(w, req)
+	}()
+
+	// The handler finishes while the client has yet to read a single event
+	select {
+	case <-handlerDone:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to run ahead of the slow client")
+	}
+
+	close(w.release)
+	<-served
+
+	// submitted, working, the chunks and the final status all arrive
+	results := streamResults(t, w.Body.String())
+	if len(results) != chunks+3 {
+		t.Fatalf("Expected %d events, got %d: %s", chunks+3, len(results), w.Body.String())
+	}
+	for i, raw := range results[2 : 2+chunks] {
+		var event models.TaskArtifactUpdateEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			t.Fatalf("Failed to unmarshal artifact event: %v", err)
+		}
+		if want := fmt.Sprintf("chunk %d", i); len(event.Artifact.Parts) != 1 || *event.Artifact.Parts[0].Text != want {
+			t.Errorf("Expected artifact event %q, got %s", want, raw)
+		}
+	}
+}
+
+// flushCountingWriter counts flushes and how much of the body each one pushed out
+type flushCountingWriter struct {
+	*httptest.ResponseRecorder
+	flushes    int
+	flushedLen int
+}
+
+func (w *flushCountingWriter) Flush() {
+	w.flushes++
+	w.flushedLen = w.Body.Len()
+	w.ResponseRecorder.Flush()
+}
+
+// tokenStreamingHandler emits n single-token artifact events
+func tokenStreamingHandler(n int) StreamingTaskHandler {
+	return func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		for i := 0; i < n; i++ {
+			events <- models.TaskArtifactUpdateEvent{
+				ID:       task.ID,
+				Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr(fmt.Sprintf("token %d", i))}}},
+			}
+		}
+		return nil
+	}
+}
+
+func TestFlushCoalescing(t *testing.T) {
+	const tokens = 100
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler,
+		WithStreamingHandler(tokenStreamingHandler(tokens)), WithFlushCoalescing(time.Hour, 10))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodMessageStream,
+		Params: models.TaskSendParams{
+			ID:      "test-task",
+			Message: models.NewTextMessage(models.RoleUser, "Hello"),
+		},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	w := &flushCountingWriter{ResponseRecorder: httptest.NewRecorder()}
+
+	server.ServeHTTP(w, req)
+
+	// submitted, working, the tokens and the final status all arrive in order
+	results := streamResults(t, w.Body.String())
+	if len(results) != tokens+3 {
+		t.Fatalf("Expected %d events, got %d", tokens+3, len(results))
+	}
+	for i, raw := range results[2 : 2+tokens] {
+		var event models.TaskArtifactUpdateEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			t.Fatalf("Failed to unmarshal artifact event: %v", err)
+		}
+		if want := fmt.Sprintf("token %d", i); len(event.Artifact.Parts) != 1 || *event.Artifact.Parts[0].Text != want {
+			t.Errorf("Expected artifact event %q, got %s", want, raw)
+		}
+	}
+
+	if w.flushes == 0 || w.flushes > len(results)/10+1 {
+		t.Errorf("Expected flushes to be batched, got %d for %d events", w.flushes, len(results))
+	}
+	// The window is far too long to have fired, so the final event must
+	// have been flushed on its own account
+	if w.flushedLen != w.Body.Len() {
+		t.Errorf("Expected the final event to be flushed, %d of %d bytes were", w.flushedLen, w.Body.Len())
+	}
+}
+
+func BenchmarkStreamingFlush(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "every event"},
+		{name: "coalesced", opts: []Option{WithFlushCoalescing(10*time.Millisecond, 32)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			opts := append([]Option{WithStreamingHandler(tokenStreamingHandler(256))}, bench.opts...)
+			server := NewA2AServer(mockAgentCard, mockErrorTaskHandler, opts...)
+			flushes := 0
+			for i := 0; i < b.N; i++ {
+				// A fresh task each time keeps artifacts from piling up
+				reqBody, _ := json.Marshal(models.JSONRPCRequest{
+					JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+					Method:         models.MethodMessageStream,
+					Params: models.TaskSendParams{
+						ID:      fmt.Sprintf("task-%d", i),
+						Message: models.NewTextMessage(models.RoleUser, "Hello"),
+					},
+				})
+				req := httptest.NewRequest("POST", "/", bytes.NewReader(reqBody))
+				w := &flushCountingWriter{ResponseRecorder: httptest.NewRecorder()}
+				server.ServeHTTP(w, req)
+				flushes += w.flushes
+			}
+			b.ReportMetric(float64(flushes)/float64(b.N), "flushes/op")
+		})
+	}
+}
+
+func TestHandleTaskDelete(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:               "test-task",
+		Message:          models.NewTextMessage(models.RoleUser, "Hello"),
+		PushNotification: &models.PushNotificationConfig{URL: "http://localhost:9999/notify"},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksDelete, models.TaskIDParams{ID: "test-task"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var deleted models.TaskIDParams
+	decodeResult(t, response, &deleted)
+	if deleted.ID != "test-task" {
+		t.Errorf("Expected deleted ID test-task, got %s", deleted.ID)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected TaskNotFound after delete, got %v", response.Error)
+	}
+	if history, _ := server.taskStore.History("test-task"); len(history) != 0 {
+		t.Errorf("Expected history to be deleted, got %d messages", len(history))
+	}
+	if _, exists, _ := server.pushConfigs.PushConfig("test-task"); exists {
+		t.Error("Expected push config to be deleted")
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksDelete, models.TaskIDParams{ID: "test-task"})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Errorf("Expected TaskNotFound deleting a missing task, got %v", response.Error)
+	}
+}
+
+func TestSendResponseWithoutResult(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := httptest.NewRecorder()
+
+	server.sendResponse(w, "1", nil)
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected internal error in place of an empty response, got %+v", response)
+	}
+	if response.Result != nil {
+		t.Errorf("Expected no result, got %v", response.Result)
+	}
+}
+
+func TestInvalidMessageRejected(t *testing.T) {
+	called := false
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		called = true
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	tests := []struct {
+		name    string
+		method  string
+		message models.Message
+	}{
+		{"empty parts", models.MethodMessageSend, models.Message{Role: models.RoleUser, Parts: []models.Part{}}},
+		{"unknown role", models.MethodMessageSend, models.Message{Role: "robot", Parts: []models.Part{{Text: stringPtr("Hello")}}}},
+		{"streaming empty parts", models.MethodMessageStream, models.Message{Role: models.RoleUser}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doJSONRPC(t, server, tt.method, models.TaskSendParams{ID: "test-task", Message: tt.message})
+			if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+				t.Errorf("Expected InvalidParams, got %+v", response.Error)
+			}
+		})
+	}
+
+	if called {
+		t.Error("Expected handler not to be called for invalid messages")
+	}
+}
+
+func TestInputRequiredMessageReturned(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		prompt := models.NewTextMessage(models.RoleAgent, "Which city?")
+		task.Status.State = models.TaskStateInputRequired
+		task.Status.Message = &prompt
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Book a flight"),
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if prompt, ok := task.InputPrompt(); !ok || prompt == nil || prompt.Text() != "Which city?" {
+		t.Errorf("Expected input prompt in send response, got %+v", task.Status)
+	}
+
+	// The prompt stays available to clients polling the task
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	decodeResult(t, response, &task)
+	if prompt, ok := task.InputPrompt(); !ok || prompt == nil || prompt.Text() != "Which city?" {
+		t.Errorf("Expected input prompt from tasks/get, got %+v", task.Status)
+	}
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	req := httptest.NewRequest("GET", healthPath, nil)
+	w := httptest.NewRecorder()
+	server.newHTTPServer().Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var health models.HealthStatus
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatalf("Failed to decode health status: %v", err)
+	}
+	if health.Status != "ok" || health.Version != mockAgentCard.Version {
+		t.Errorf("Expected ok at version %s, got %+v", mockAgentCard.Version, health)
+	}
+}
+
+func TestHandlerArtifactsPersisted(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		task.Artifacts = append(task.Artifacts, models.Artifact{
+			Name:  stringPtr("report.txt"),
+			Parts: []models.Part{models.NewTextPart("Quarterly numbers")},
+		})
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Write the report"),
+	})
+	var sent models.Task
+	decodeResult(t, response, &sent)
+	if len(sent.Artifacts) != 1 || *sent.Artifacts[0].Name != "report.txt" {
+		t.Fatalf("Expected artifact in send response, got %+v", sent.Artifacts)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	var got models.Task
+	decodeResult(t, response, &got)
+	if len(got.Artifacts) != 1 || *got.Artifacts[0].Parts[0].Text != "Quarterly numbers" {
+		t.Errorf("Expected artifact from tasks/get, got %+v", got.Artifacts)
+	}
+
+	// Continuing the task keeps earlier artifacts alongside new ones
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "And another"),
+	})
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	decodeResult(t, response, &got)
+	if len(got.Artifacts) != 2 {
+		t.Errorf("Expected 2 artifacts after a second message, got %d", len(got.Artifacts))
+	}
+}
+
+func TestNonBlockingSend(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:       "test-task",
+		Message:  models.NewTextMessage(models.RoleUser, "Hello"),
+		Metadata: map[string]interface{}{models.MetadataBlocking: false},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateWorking {
+		t.Fatalf("Expected the immediate response to be %s, got %s", models.TaskStateWorking, task.Status.State)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for task.Status.State != models.TaskStateCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the task to complete, still %s", task.Status.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+		response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+		decodeResult(t, response, &task)
+	}
+}
+
+func TestNonBlockingSendCancel(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:       "test-task",
+		Message:  models.NewTextMessage(models.RoleUser, "Hello"),
+		Metadata: map[string]interface{}{models.MetadataBlocking: false},
+	})
+
+	// The detached handler is interrupted by tasks/cancel and the task stays canceled
+	response := doJSONRPC(t, server, models.MethodTasksCancel, models.TaskIDParams{ID: "test-task"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.RLock()
+		_, running := server.cancelFuncs["test-task"]
+		server.mu.RUnlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the handler to stop after cancel")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCanceled, task.Status.State)
+	}
+}
+
+func TestIdempotentSends(t *testing.T) {
+	runs := 0
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		runs++
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Charge the card"),
+	}
+
+	server := NewA2AServer(mockAgentCard, handler, WithIdempotentSends())
+	first := doJSONRPC(t, server, models.MethodMessageSend, params)
+	second := doJSONRPC(t, server, models.MethodMessageSend, params)
+
+	if runs != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", runs)
+	}
+	var firstTask, secondTask models.Task
+	decodeResult(t, first, &firstTask)
+	decodeResult(t, second, &secondTask)
+	if secondTask.Status.State != models.TaskStateCompleted || !secondTask.Status.Timestamp.Equal(*firstTask.Status.Timestamp) {
+		t.Errorf("Expected the stored result, got %+v", secondTask.Status)
+	}
+
+	// Without the option a repeated send is rejected, as the task finished
+	runs = 0
+	server = NewA2AServer(mockAgentCard, handler)
+	doJSONRPC(t, server, models.MethodMessageSend, params)
+	response := doJSONRPC(t, server, models.MethodMessageSend, params)
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected invalid params error for a finished task, got %+v", response.Error)
+	}
+	if runs != 1 {
+		t.Errorf("Expected handler to run once without idempotency, ran %d times", runs)
+	}
+}
+
+func TestSendToFinishedTask(t *testing.T) {
+	runs := 0
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		runs++
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+	params := models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	}
+	doJSONRPC(t, server, models.MethodMessageSend, params)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, params)
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected invalid params error for message/send, got %+v", response.Error)
+	}
+
+	w := doStream(t, server, params)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected a plain JSON-RPC error instead of a stream, got content type %q", ct)
+	}
+	var streamResponse models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&streamResponse); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if streamResponse.Error == nil || streamResponse.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected invalid params error for message/stream, got %+v", streamResponse.Error)
+	}
+
+	// The task was neither run again nor moved out of its terminal state
+	if runs != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", runs)
+	}
+	task, _, _ := store.Get("test-task")
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task to stay completed, got %s", task.Status.State)
+	}
+	transitions, _ := store.Transitions("test-task")
+	if len(transitions) != 2 || transitions[1].State != models.TaskStateCompleted {
+		t.Errorf("Expected working then completed only, got %+v", transitions)
+	}
+	if history, _ := store.History("test-task"); len(history) != 1 {
+		t.Errorf("Expected rejected messages to stay out of the history, got %d messages", len(history))
+	}
+}
+
+// failingSaveStore is a task store whose Save fails once failing is set
+type failingSaveStore struct {
+	*InMemoryTaskStore
+	failing atomic.Bool
+}
+
+func (s *failingSaveStore) Save(task *models.Task) error {
+	if s.failing.Load() {
+		return errors.New("disk full")
+	}
+	return s.InMemoryTaskStore.Save(task)
+}
+
+func TestHandlerErrorStoreFailure(t *testing.T) {
+	store := &failingSaveStore{InMemoryTaskStore: NewInMemoryTaskStore()}
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		store.failing.Store(true)
+		return nil, errors.New("model unavailable")
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error == nil || !strings.Contains(response.Error.Message, "disk full") {
+		t.Errorf("Expected the store failure to be reported, got %+v", response.Error)
+	}
+}
+
+func TestHandlerReturnsNoTask(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		return nil, nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "send-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected internal error for message/send, got %+v", response.Error)
+	}
+	if task, _, _ := store.Get("send-task"); task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected message/send task to fail, got %s", task.Status.State)
+	}
+
+	w := doStream(t, server, models.TaskSendParams{
+		ID:      "stream-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	results := streamResults(t, w.Body.String())
+	var final models.TaskStatusUpdateEvent
+	json.Unmarshal(results[len(results)-1], &final)
+	if final.Status.State != models.TaskStateFailed || final.Final == nil || !*final.Final {
+		t.Errorf("Expected final failed event for message/stream, got %+v", final)
+	}
+	if task, _, _ := store.Get("stream-task"); task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected message/stream task to fail, got %s", task.Status.State)
+	}
+}
+
+func TestHandlerReturnsInvalidTask(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		return &models.Task{Status: newTaskStatus(models.TaskStateCompleted)}, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected internal error for an invalid task, got %+v", response.Error)
+	}
+}
+
+func TestStreamingHandlerGetsTaskCopy(t *testing.T) {
+	var handlerTask *models.Task
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		handlerTask = task
+		// Handlers may scribble on their task; the server must not see it
+		task.Status.State = models.TaskStateFailed
+		task.Metadata = map[string]interface{}{"scratch": true}
+		events <- models.TaskStatusUpdateEvent{ID: task.ID, Status: models.TaskStatus{State: models.TaskStateCompleted}, Final: boolPtr(true)}
+		return nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store), WithStreamingHandler(streamingHandler))
+
+	doStream(t, server, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+
+	task, _, _ := store.Get("test-task")
+	if task.Status.State != models.TaskStateCompleted || task.Metadata != nil {
+		t.Errorf("Expected only the handler's events to reach the task, got %+v", task)
+	}
+	if handlerTask == nil || handlerTask.ID != "test-task" {
+		t.Errorf("Expected the handler to get the task, got %+v", handlerTask)
+	}
+}
+
+func TestWithoutImplicitWorking(t *testing.T) {
+	var initial models.TaskState
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		initial = task.Status.State
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store), WithoutImplicitWorking())
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+
+	if initial != models.TaskStateSubmitted {
+		t.Errorf("Expected the handler to see a submitted task, got %s", initial)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected completed, got %s", task.Status.State)
+	}
+	transitions, _ := store.Transitions("test-task")
+	if len(transitions) != 2 || transitions[0].State != models.TaskStateSubmitted || transitions[1].State != models.TaskStateCompleted {
+		t.Errorf("Expected submitted then completed with no working state, got %+v", transitions)
+	}
+
+	// Streams skip the working event too
+	w := doStream(t, server, models.TaskSendParams{
+		ID:      "stream-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	for _, result := range streamResults(t, w.Body.String()) {
+		var event models.TaskStatusUpdateEvent
+		if json.Unmarshal(result, &event) == nil && event.Status.State == models.TaskStateWorking {
+			t.Errorf("Expected no working event, got %s", result)
+		}
+	}
+}
+
+func TestWithoutImplicitWorkingMultiTurn(t *testing.T) {
+	// The first turn asks for input, the second completes the task
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		if *message.Parts[0].Text == "Hello" {
+			task.Status.State = models.TaskStateInputRequired
+		} else {
+			task.Status.State = models.TaskStateCompleted
+		}
+		return task, nil
+	}
+	streamingHandler := func(ctx context.Context, task *models.Task, message *models.Message, events chan<- any) error {
+		state := models.TaskStateCompleted
+		if *message.Parts[0].Text == "Hello" {
+			state = models.TaskStateInputRequired
+		}
+		events <- models.TaskStatusUpdateEvent{ID: task.ID, Status: models.TaskStatus{State: state}, Final: boolPtr(true)}
+		return nil
+	}
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store), WithStreamingHandler(streamingHandler), WithoutImplicitWorking())
+
+	for _, text := range []string{"Hello", "Here you go"} {
+		response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID:      "send-task",
+			Message: models.NewTextMessage(models.RoleUser, text),
+		})
+		if response.Error != nil {
+			t.Fatalf("Expected no error for %q, got %v", text, response.Error)
+		}
+	}
+	task, _, _ := store.Get("send-task")
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected message/send task to complete on the second turn, got %s", task.Status.State)
+	}
+
+	for _, text := range []string{"Hello", "Here you go"} {
+		doStream(t, server, models.TaskSendParams{
+			ID:      "stream-task",
+			Message: models.NewTextMessage(models.RoleUser, text),
+		})
+	}
+	task, _, _ = store.Get("stream-task")
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected message/stream task to complete on the second turn, got %s", task.Status.State)
+	}
+	transitions, _ := store.Transitions("stream-task")
+	var states []models.TaskState
+	for _, status := range transitions {
+		states = append(states, status.State)
+	}
+	want := []models.TaskState{models.TaskStateSubmitted, models.TaskStateInputRequired, models.TaskStateCompleted}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Expected transitions %v, got %v", want, states)
+	}
+}
+
+func TestWithoutImplicitWorkingLeftSubmitted(t *testing.T) {
+	// A handler that queues the task for later leaves it submitted
+	handler := func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithoutImplicitWorking())
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateSubmitted {
+		t.Errorf("Expected the task to stay submitted, got %s", task.Status.State)
+	}
+}
+
+// doJSONRPC sends a JSON-RPC request to the server and decodes the response
+func doJSONRPC(t *testing.T, server *A2AServer, method string, params interface{}) models.JSONRPCResponse {
+	t.Helper()
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: method,
+		Params: params,
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+// doStream sends a message/stream request to the server and returns the recorded response
+func doStream(t *testing.T, server *A2AServer, params models.TaskSendParams) *httptest.ResponseRecorder {
+	t.Helper()
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: models.MethodMessageStream,
+		Params: params,
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+	return w
+}
+
+// streamResults extracts the result of each event in a streaming response body
+func streamResults(t *testing.T, body string) []json.RawMessage {
+	t.Helper()
+
+	var results []json.RawMessage
+	for _, line := range sseData(t, body) {
+		var response struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			t.Fatalf("Failed to unmarshal stream event %q: %v", line, err)
+		}
+		results = append(results, response.Result)
+	}
+	return results
+}
+
+// sseData returns the data payload of each SSE frame in body, failing on malformed framing
+func sseData(t *testing.T, body string) []string {
+	t.Helper()
+
+	var payloads []string
+	for _, frame := range sseFrames(t, body) {
+		payloads = append(payloads, frame.data)
+	}
+	return payloads
+}
+
+// sseFrame is an SSE frame of a single data line and an optional ID
+type sseFrame struct {
+	id   string
+	data string
+}
+
+// sseFrames splits body into SSE frames, failing on malformed framing
+func sseFrames(t *testing.T, body string) []sseFrame {
+	t.Helper()
+
+	var frames []sseFrame
+	for _, frame := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		var f sseFrame
+		if rest, ok := strings.CutPrefix(frame, "id: "); ok {
+			f.id, frame, _ = strings.Cut(rest, "\n")
+		}
+		if !strings.HasPrefix(frame, "data: ") || strings.Contains(frame, "\n") {
+			t.Fatalf("Malformed SSE frame %q", frame)
+		}
+		f.data = strings.TrimPrefix(frame, "data: ")
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// decodeResult re-decodes a generic JSON-RPC result into out
+func decodeResult(t *testing.T, response models.JSONRPCResponse, out interface{}) {
+	t.Helper()
+
+	resultBytes, err := json.Marshal(response.Result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+	if err := json.Unmarshal(resultBytes, out); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
 }
 
 func testStringPtr(s string) *string {
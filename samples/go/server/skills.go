@@ -0,0 +1,30 @@
+package server
+
+import "a2a/models"
+
+// skillIDKey is the metadata key naming the skill a message is meant for
+const skillIDKey = "skillId"
+
+// RegisterSkillHandler routes messages for skillID to h instead of the
+// server's default handler. The skill is taken from the skillId field of the
+// request metadata; requests naming no skill, or a skill without a
+// registered handler, go to the default handler. A skill handler also serves message/stream requests
+// for its skill in place of the streaming handler. RegisterSkillHandler must
+// be called before serving.
+func (s *A2AServer) RegisterSkillHandler(skillID string, h TaskHandler) {
+	if s.skillHandlers == nil {
+		s.skillHandlers = make(map[string]TaskHandler)
+	}
+	s.skillHandlers[skillID] = h
+}
+
+// skillHandler returns the handler registered for the skill params are
+// addressed to, if any
+func (s *A2AServer) skillHandler(params models.TaskSendParams) (TaskHandler, bool) {
+	skillID, ok := params.Metadata[skillIDKey].(string)
+	if !ok {
+		return nil, false
+	}
+	h, ok := s.skillHandlers[skillID]
+	return h, ok
+}
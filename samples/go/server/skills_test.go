@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestRegisterSkillHandler(t *testing.T) {
+	var ran []string
+	handlerFor := func(name string) TaskHandler {
+		return func(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+			ran = append(ran, name)
+			task.Status = newTaskStatus(models.TaskStateCompleted)
+			return task, nil
+		}
+	}
+
+	server := NewA2AServer(mockAgentCard, handlerFor("default"))
+	server.RegisterSkillHandler("translate", handlerFor("translate"))
+	server.RegisterSkillHandler("summarize", handlerFor("summarize"))
+
+	send := func(taskID string, metadata map[string]interface{}) {
+		t.Helper()
+		response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+			ID:       taskID,
+			Message:  models.NewTextMessage(models.RoleUser, "Hello"),
+			Metadata: metadata,
+		})
+		if response.Error != nil {
+			t.Fatalf("Expected no error, got %v", response.Error)
+		}
+	}
+
+	send("task-1", map[string]interface{}{"skillId": "summarize"})
+	send("task-2", map[string]interface{}{"skillId": "translate"})
+	send("task-3", map[string]interface{}{"skillId": "unknown"})
+	send("task-4", nil)
+
+	expected := []string{"summarize", "translate", "default", "default"}
+	if len(ran) != len(expected) {
+		t.Fatalf("Expected handlers %v, got %v", expected, ran)
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Errorf("Expected handlers %v, got %v", expected, ran)
+			break
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package sqlitestore provides a task store for a2a/server backed by a SQLite
+// database file. It lives in its own package so that only programs using it
+// link the SQLite driver.
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	// Registers the pure-Go "sqlite" driver, so no cgo is needed
+	_ "modernc.org/sqlite"
+
+	"a2a/models"
+	"a2a/server"
+)
+
+var (
+	_ server.TaskStore       = (*Store)(nil)
+	_ server.PushConfigStore = (*Store)(nil)
+)
+
+// schema creates the tables a Store needs. Tasks, messages, statuses and push
+// configs are kept as JSON; history and transitions keep their insertion
+// order through the autoincrementing seq column.
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id   TEXT PRIMARY KEY,
+	task TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_task_id ON history (task_id);
+CREATE TABLE IF NOT EXISTS transitions (
+	seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id TEXT NOT NULL,
+	status  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transitions_task_id ON transitions (task_id);
+CREATE TABLE IF NOT EXISTS push_configs (
+	task_id TEXT PRIMARY KEY,
+	config  TEXT NOT NULL
+);
+`
+
+// Store is a server.TaskStore backed by a SQLite database file, letting a
+// single server keep its tasks across restarts. It also implements
+// server.PushConfigStore, so push notification configs survive restarts too.
+type Store struct {
+	db *sql.DB
+}
+
+// New opens the SQLite database at path, creating the file and its tables if
+// they don't exist yet. Close the store when done.
+func New(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; a single connection serializes
+	// writes instead of failing them as busy
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save creates or replaces a task
+func (s *Store) Save(task *models.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO tasks (id, task) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET task = excluded.task`, task.ID, data)
+	return err
+}
+
+// Get returns the task with the given ID and whether it exists
+func (s *Store) Get(id string) (*models.Task, bool, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT task FROM tasks WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var task models.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, false, err
+	}
+	return &task, true, nil
+}
+
+// AppendHistory appends a message to a task's history
+func (s *Store) AppendHistory(id string, msg *models.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO history (task_id, message) VALUES (?, ?)`, id, data)
+	return err
+}
+
+// History returns a task's messages in chronological order
+func (s *Store) History(id string) ([]*models.Message, error) {
+	rows, err := s.db.Query(`SELECT message FROM history WHERE task_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*models.Message{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var msg models.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		history = append(history, &msg)
+	}
+	return history, rows.Err()
+}
+
+// AppendTransition records a change in a task's status
+func (s *Store) AppendTransition(id string, status models.TaskStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO transitions (task_id, status) VALUES (?, ?)`, id, data)
+	return err
+}
+
+// Transitions returns a task's status changes in chronological order
+func (s *Store) Transitions(id string) ([]models.TaskStatus, error) {
+	rows, err := s.db.Query(`SELECT status FROM transitions WHERE task_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []models.TaskStatus{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var status models.TaskStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, status)
+	}
+	return transitions, rows.Err()
+}
+
+// List returns all stored tasks in no particular order
+func (s *Store) List() ([]*models.Task, error) {
+	rows, err := s.db.Query(`SELECT task FROM tasks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var task models.Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, rows.Err()
+}
+
+// Delete removes a task along with its history, transitions and push config
+func (s *Store) Delete(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM tasks WHERE id = ?`,
+		`DELETE FROM history WHERE task_id = ?`,
+		`DELETE FROM transitions WHERE task_id = ?`,
+		`DELETE FROM push_configs WHERE task_id = ?`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SavePushConfig creates or replaces a task's push notification config
+func (s *Store) SavePushConfig(taskID string, config models.PushNotificationConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO push_configs (task_id, config) VALUES (?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET config = excluded.config`, taskID, data)
+	return err
+}
+
+// PushConfig returns a task's push notification config and whether it has one
+func (s *Store) PushConfig(taskID string) (models.PushNotificationConfig, bool, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT config FROM push_configs WHERE task_id = ?`, taskID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PushNotificationConfig{}, false, nil
+	}
+	if err != nil {
+		return models.PushNotificationConfig{}, false, err
+	}
+
+	var config models.PushNotificationConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return models.PushNotificationConfig{}, false, err
+	}
+	return config, true, nil
+}
+
+// DeletePushConfig removes a task's push notification config, if any
+func (s *Store) DeletePushConfig(taskID string) error {
+	_, err := s.db.Exec(`DELETE FROM push_configs WHERE task_id = ?`, taskID)
+	return err
+}
@@ -0,0 +1,170 @@
+package sqlitestore
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"a2a/client"
+	"a2a/models"
+	"a2a/server"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	store, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	now := time.Now().UTC()
+	task := &models.Task{
+		ID:        "test-task-1",
+		SessionID: stringPtr("session-1"),
+		Status:    models.TaskStatus{State: models.TaskStateCompleted, Timestamp: &now},
+	}
+	if err := store.Save(task); err != nil {
+		t.Fatalf("Failed to save task: %v", err)
+	}
+	for _, text := range []string{"first", "second"} {
+		msg := models.NewTextMessage(models.RoleUser, text)
+		if err := store.AppendHistory(task.ID, &msg); err != nil {
+			t.Fatalf("Failed to append history: %v", err)
+		}
+	}
+	store.AppendTransition(task.ID, models.TaskStatus{State: models.TaskStateWorking})
+	store.AppendTransition(task.ID, task.Status)
+	config := models.PushNotificationConfig{URL: "https://example.com/hook", Token: stringPtr("secret")}
+	if err := store.SavePushConfig(task.ID, config); err != nil {
+		t.Fatalf("Failed to save push config: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	// Reopening the file simulates a server restart
+	store, err = New(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	got, exists, err := store.Get(task.ID)
+	if err != nil || !exists {
+		t.Fatalf("Expected task to exist, got exists=%v err=%v", exists, err)
+	}
+	if got.Status.State != models.TaskStateCompleted || got.SessionID == nil || *got.SessionID != "session-1" {
+		t.Errorf("Expected stored task back, got %+v", got)
+	}
+
+	history, err := store.History(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 || *history[0].Parts[0].Text != "first" || *history[1].Parts[0].Text != "second" {
+		t.Errorf("Expected history in chronological order, got %v", history)
+	}
+
+	transitions, err := store.Transitions(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get transitions: %v", err)
+	}
+	if len(transitions) != 2 || transitions[1].State != models.TaskStateCompleted {
+		t.Errorf("Expected 2 transitions ending in completed, got %v", transitions)
+	}
+
+	gotConfig, exists, err := store.PushConfig(task.ID)
+	if err != nil || !exists {
+		t.Fatalf("Expected push config to exist, got exists=%v err=%v", exists, err)
+	}
+	if gotConfig.URL != config.URL || gotConfig.Token == nil || *gotConfig.Token != "secret" {
+		t.Errorf("Expected stored push config back, got %+v", gotConfig)
+	}
+
+	tasks, err := store.List()
+	if err != nil || len(tasks) != 1 {
+		t.Errorf("Expected 1 listed task, got %d (err=%v)", len(tasks), err)
+	}
+
+	if err := store.Delete(task.ID); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if _, exists, _ := store.Get(task.ID); exists {
+		t.Error("Expected task to be deleted")
+	}
+	if history, _ := store.History(task.ID); len(history) != 0 {
+		t.Errorf("Expected history to be deleted, got %d messages", len(history))
+	}
+	if _, exists, _ := store.PushConfig(task.ID); exists {
+		t.Error("Expected push config to be deleted")
+	}
+}
+
+func echoHandler(ctx context.Context, task *models.Task, message *models.Message) (*models.Task, error) {
+	task.Status.State = models.TaskStateCompleted
+	return task, nil
+}
+
+// startServer serves a fresh A2AServer over the store at path, as a restarted
+// process would
+func startServer(t *testing.T, path string) *client.Client {
+	t.Helper()
+	store, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	push := true
+	card := models.AgentCard{Name: "Test Agent", Capabilities: models.AgentCapabilities{PushNotifications: &push}}
+	srv := server.NewA2AServer(card, echoHandler, server.WithTaskStore(store))
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		store.Close()
+	})
+	return client.NewClient(ts.URL)
+}
+
+func TestServerWithStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	c := startServer(t, path)
+	if _, err := c.SendTask(models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	}); err != nil {
+		t.Fatalf("Failed to send task: %v", err)
+	}
+	if _, err := c.SetPushNotification(models.TaskPushNotificationConfig{
+		ID:                     "test-task-1",
+		PushNotificationConfig: models.PushNotificationConfig{URL: "https://example.com/hook"},
+	}); err != nil {
+		t.Fatalf("Failed to set push notification: %v", err)
+	}
+
+	c = startServer(t, path)
+	resp, err := c.GetTask(models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	task, err := resp.AsTask()
+	if err != nil {
+		t.Fatalf("Expected a task, got %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted || len(task.History) != 1 {
+		t.Errorf("Expected completed task with its history, got %+v", task)
+	}
+
+	config, err := c.GetPushNotification(models.TaskIDParams{ID: "test-task-1"})
+	if err != nil {
+		t.Fatalf("Expected push config to survive the restart, got %v", err)
+	}
+	if config.URL != "https://example.com/hook" {
+		t.Errorf("Expected stored push config, got %+v", config)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"a2a/models"
+)
+
+// TaskStore persists tasks and their message history
+type TaskStore interface {
+	// Save creates or replaces a task
+	Save(task *models.Task) error
+	// Get returns the task with the given ID and whether it exists
+	Get(id string) (*models.Task, bool, error)
+	// AppendHistory appends a message to a task's history
+	AppendHistory(id string, msg *models.Message) error
+	// History returns a task's messages in chronological order
+	History(id string) ([]*models.Message, error)
+	// AppendTransition records a change in a task's status
+	AppendTransition(id string, status models.TaskStatus) error
+	// Transitions returns a task's status changes in chronological order
+	Transitions(id string) ([]models.TaskStatus, error)
+	// List returns all stored tasks in no particular order
+	List() ([]*models.Task, error)
+	// Delete removes a task along with its history and transitions
+	Delete(id string) error
+}
+
+// InMemoryTaskStore is a TaskStore backed by in-process maps
+type InMemoryTaskStore struct {
+	tasks       map[string]*models.Task
+	history     map[string][]*models.Message
+	transitions map[string][]models.TaskStatus
+	mu          sync.RWMutex
+}
+
+// NewInMemoryTaskStore creates an empty in-memory task store
+func NewInMemoryTaskStore() *InMemoryTaskStore {
+	return &InMemoryTaskStore{
+		tasks:       make(map[string]*models.Task),
+		history:     make(map[string][]*models.Message),
+		transitions: make(map[string][]models.TaskStatus),
+	}
+}
+
+// Save stores a copy of the task so later mutations by the caller are not shared
+func (m *InMemoryTaskStore) Save(task *models.Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tasks[task.ID] = cloneTask(task)
+	return nil
+}
+
+// Get returns a copy of the stored task
+func (m *InMemoryTaskStore) Get(id string) (*models.Task, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[id]
+	if !exists {
+		return nil, false, nil
+	}
+	return cloneTask(task), true, nil
+}
+
+// AppendHistory appends a message to a task's history
+func (m *InMemoryTaskStore) AppendHistory(id string, msg *models.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history[id] = append(m.history[id], msg)
+	return nil
+}
+
+// History returns a copy of a task's message history
+func (m *InMemoryTaskStore) History(id string) ([]*models.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]*models.Message, len(m.history[id]))
+	copy(history, m.history[id])
+	return history, nil
+}
+
+// AppendTransition records a change in a task's status
+func (m *InMemoryTaskStore) AppendTransition(id string, status models.TaskStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transitions[id] = append(m.transitions[id], status)
+	return nil
+}
+
+// Transitions returns a copy of a task's status changes
+func (m *InMemoryTaskStore) Transitions(id string) ([]models.TaskStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	transitions := make([]models.TaskStatus, len(m.transitions[id]))
+	copy(transitions, m.transitions[id])
+	return transitions, nil
+}
+
+// List returns copies of all stored tasks
+func (m *InMemoryTaskStore) List() ([]*models.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tasks := make([]*models.Task, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		tasks = append(tasks, cloneTask(task))
+	}
+	return tasks, nil
+}
+
+// Delete removes a task along with its history and transitions
+func (m *InMemoryTaskStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tasks, id)
+	delete(m.history, id)
+	delete(m.transitions, id)
+	return nil
+}
+
+// cloneTask copies a task deeply enough that the store and its callers never
+// share artifacts, their parts, history or metadata
+func cloneTask(task *models.Task) *models.Task {
+	clone := *task
+	clone.Artifacts = slices.Clone(task.Artifacts)
+	for i := range clone.Artifacts {
+		clone.Artifacts[i].Parts = slices.Clone(clone.Artifacts[i].Parts)
+	}
+	clone.History = slices.Clone(task.History)
+	clone.StatusHistory = slices.Clone(task.StatusHistory)
+	clone.Metadata = maps.Clone(task.Metadata)
+	return &clone
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"testing"
+
+	"a2a/models"
+)
+
+func TestInMemoryTaskStore(t *testing.T) {
+	store := NewInMemoryTaskStore()
+
+	if _, exists, err := store.Get("missing"); err != nil || exists {
+		t.Fatalf("Expected missing task, got exists=%v err=%v", exists, err)
+	}
+
+	task := &models.Task{
+		ID: "test-task-1",
+		Status: models.TaskStatus{
+			State: models.TaskStateWorking,
+		},
+		Artifacts: []models.Artifact{{Name: stringPtr("draft")}},
+	}
+	if err := store.Save(task); err != nil {
+		t.Fatalf("Failed to save task: %v", err)
+	}
+
+	// Mutating the caller's copy must not affect the stored task
+	task.Status.State = models.TaskStateCompleted
+	task.Artifacts[0].Name = stringPtr("final")
+
+	got, exists, err := store.Get("test-task-1")
+	if err != nil || !exists {
+		t.Fatalf("Expected task to exist, got exists=%v err=%v", exists, err)
+	}
+	if got.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateWorking, got.Status.State)
+	}
+	if *got.Artifacts[0].Name != "draft" {
+		t.Errorf("Expected stored artifact draft, got %s", *got.Artifacts[0].Name)
+	}
+
+	for _, text := range []string{"first", "second"} {
+		msg := &models.Message{Role: models.RoleUser, Parts: []models.Part{{Text: stringPtr(text)}}}
+		if err := store.AppendHistory("test-task-1", msg); err != nil {
+			t.Fatalf("Failed to append history: %v", err)
+		}
+	}
+
+	history, err := store.History("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	}
+	if *history[0].Parts[0].Text != "first" || *history[1].Parts[0].Text != "second" {
+		t.Errorf("Expected history in chronological order, got %v", history)
+	}
+}
+
+func TestA2AServer_WithTaskStore(t *testing.T) {
+	store := NewInMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+
+	doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID: "test-task-1",
+		Message: models.Message{
+			Role:  models.RoleUser,
+			Parts: []models.Part{{Text: stringPtr("Hello")}},
+		},
+	})
+
+	task, exists, err := store.Get("test-task-1")
+	if err != nil || !exists {
+		t.Fatalf("Expected task in custom store, got exists=%v err=%v", exists, err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+
+	history, _ := store.History("test-task-1")
+	if len(history) != 1 {
+		t.Errorf("Expected 1 history entry, got %d", len(history))
+	}
+}
+
+func TestInMemoryTaskStoreListDelete(t *testing.T) {
+	store := NewInMemoryTaskStore()
+	for _, id := range []string{"a", "b"} {
+		store.Save(&models.Task{ID: id})
+		store.AppendHistory(id, &models.Message{Role: models.RoleUser})
+		store.AppendTransition(id, models.TaskStatus{State: models.TaskStateWorking})
+	}
+
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(tasks))
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if _, exists, _ := store.Get("a"); exists {
+		t.Error("Expected deleted task to be gone")
+	}
+	if history, _ := store.History("a"); len(history) != 0 {
+		t.Errorf("Expected deleted task history to be gone, got %d entries", len(history))
+	}
+	if transitions, _ := store.Transitions("a"); len(transitions) != 0 {
+		t.Errorf("Expected deleted task transitions to be gone, got %d entries", len(transitions))
+	}
+	if _, exists, _ := store.Get("b"); !exists {
+		t.Error("Expected other task to remain")
+	}
+}
+
+func TestInMemoryTaskStoreReturnsCopies(t *testing.T) {
+	store := NewInMemoryTaskStore()
+	store.Save(&models.Task{
+		ID:        "test-task-1",
+		Status:    models.TaskStatus{State: models.TaskStateWorking},
+		Artifacts: []models.Artifact{{Parts: []models.Part{models.NewTextPart("draft")}}},
+		History:   []models.Message{models.NewTextMessage(models.RoleUser, "Hello")},
+		Metadata:  map[string]interface{}{"owner": "alice"},
+	})
+
+	// Changes to tasks from Get or List must not reach the stored task
+	got, _, _ := store.Get("test-task-1")
+	got.Artifacts[0].Parts[0] = models.NewTextPart("changed")
+	got.History[0] = models.NewTextMessage(models.RoleUser, "changed")
+	got.Metadata["owner"] = "mallory"
+
+	listed, _ := store.List()
+	listed[0].Artifacts[0].Parts[0] = models.NewTextPart("changed")
+	listed[0].History[0] = models.NewTextMessage(models.RoleUser, "changed")
+	listed[0].Metadata["owner"] = "mallory"
+
+	stored, _, _ := store.Get("test-task-1")
+	if text := *stored.Artifacts[0].Parts[0].Text; text != "draft" {
+		t.Errorf("Expected stored artifact part draft, got %s", text)
+	}
+	if text := *stored.History[0].Parts[0].Text; text != "Hello" {
+		t.Errorf("Expected stored history Hello, got %s", text)
+	}
+	if owner := stored.Metadata["owner"]; owner != "alice" {
+		t.Errorf("Expected stored metadata owner alice, got %v", owner)
+	}
+}
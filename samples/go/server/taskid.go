@@ -0,0 +1,41 @@
+package server
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"a2a/models"
+)
+
+// IDGenerator returns a new task ID, unique among the server's tasks
+type IDGenerator func() string
+
+// WithServerAssignedIDs makes the server choose the ID of tasks that
+// message/send and message/stream requests start without one, rather than
+// storing them under the empty ID. The assigned ID is returned in the task
+// and stream events. generate defaults to random UUIDs when nil.
+func WithServerAssignedIDs(generate IDGenerator) Option {
+	return func(s *A2AServer) {
+		if generate == nil {
+			generate = newUUID
+		}
+		s.idGenerator = generate
+	}
+}
+
+// assignTaskID gives params a generated task ID when it has none and the
+// server assigns IDs
+func (s *A2AServer) assignTaskID(params *models.TaskSendParams) {
+	if params.ID == "" && s.idGenerator != nil {
+		params.ID = s.idGenerator()
+	}
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestServerAssignedIDs(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithServerAssignedIDs(nil))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(task.ID) {
+		t.Fatalf("Expected a generated UUID task ID, got %q", task.ID)
+	}
+
+	response = doJSONRPC(t, server, models.MethodTasksGet, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: task.ID},
+	})
+	var fetched models.Task
+	decodeResult(t, response, &fetched)
+	if fetched.ID != task.ID || fetched.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected to fetch the completed task %s, got %+v", task.ID, fetched)
+	}
+
+	// IDs chosen by the client are kept
+	response = doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		ID:      "client-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	decodeResult(t, response, &task)
+	if task.ID != "client-task" {
+		t.Errorf("Expected the client's task ID, got %q", task.ID)
+	}
+}
+
+func TestServerAssignedIDsGenerator(t *testing.T) {
+	next := 0
+	generate := func() string {
+		next++
+		return fmt.Sprintf("task-%d", next)
+	}
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithServerAssignedIDs(generate))
+
+	response := doJSONRPC(t, server, models.MethodMessageSend, models.TaskSendParams{
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.ID != "task-1" {
+		t.Errorf("Expected task-1, got %q", task.ID)
+	}
+
+	w := doStream(t, server, models.TaskSendParams{
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+	for _, result := range streamResults(t, w.Body.String()) {
+		var event struct{ ID string }
+		if err := json.Unmarshal(result, &event); err != nil || event.ID != "task-2" {
+			t.Errorf("Expected stream events for task-2, got %s", result)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContext propagates W3C trace context between agents
+var traceContext = propagation.TraceContext{}
+
+// WithTracerProvider records an OpenTelemetry span for each JSON-RPC
+// request, continuing the trace context sent by the caller. Handlers receive
+// the span in their context so their own spans nest beneath it.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *A2AServer) {
+		s.tracer = tp.Tracer("a2a/server")
+	}
+}
+
+// startSpan starts a server span for a request and returns the request
+// carrying it. Without a tracer it returns r and a no-op span.
+func (s *A2AServer) startSpan(r *http.Request, method, taskID string) (*http.Request, trace.Span) {
+	if s.tracer == nil {
+		return r, trace.SpanFromContext(context.Background())
+	}
+
+	ctx := traceContext.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := s.tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "jsonrpc"),
+			attribute.String("rpc.method", method),
+			attribute.String("a2a.task_id", taskID),
+		),
+	)
+	return r.WithContext(ctx), span
+}
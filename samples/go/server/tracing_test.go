@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"a2a/models"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTracerProvider(tp))
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{JSONRPC: "2.0", JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"}},
+		Method:         models.MethodTasksGet,
+		Params:         models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}},
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != models.MethodTasksGet {
+		t.Errorf("Expected span name %s, got %s", models.MethodTasksGet, span.Name)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected span to continue the incoming trace, got trace ID %s", got)
+	}
+	if !hasAttribute(span.Attributes, attribute.String("a2a.task_id", "missing")) {
+		t.Errorf("Expected task ID attribute, got %v", span.Attributes)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected error status for a task not found response, got %v", span.Status.Code)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"a2a/models"
+)
+
+// websocketPath is where WithWebSocket serves the WebSocket endpoint
+const websocketPath = "/ws"
+
+// WithWebSocket serves JSON-RPC over a WebSocket connection on /ws, as an
+// alternative to HTTP requests and SSE. Each text message is one JSON-RPC
+// request and is dispatched exactly as if it had been POSTed. Plain responses
+// come back as one message; streaming methods send one message per event.
+// Requests on a connection run concurrently, so a client can answer an
+// input-required task while another stream is still open.
+func WithWebSocket() Option {
+	return func(s *A2AServer) {
+		s.websocket = true
+	}
+}
+
+// handleWebSocket upgrades the connection and serves requests until the
+// client disconnects
+func (s *A2AServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	if s.corsOrigins != nil {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(s.corsOrigins, "*") || slices.Contains(s.corsOrigins, origin)
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	// Blocking sends still running when the client goes away see ctx canceled;
+	// streams and non-blocking sends run on until tasks/cancel or Stop
+	ctx, cancel := context.WithCancel(r.Context())
+
+	// gorilla/websocket allows only one concurrent writer
+	var writeMu sync.Mutex
+	send := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	// Each message goes through the middleware, as an HTTP request would
+	dispatch := s.withMiddleware(s)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			s.logger.Info("client disconnected", "transport", "websocket", "error", err)
+			return
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveWebSocketRequest(ctx, dispatch, r, data, send)
+		}()
+	}
+}
+
+// serveWebSocketRequest dispatches one JSON-RPC request through dispatch,
+// relaying its response over the connection
+func (s *A2AServer) serveWebSocketRequest(ctx context.Context, dispatch http.Handler, upgrade *http.Request, data []byte, send func([]byte) error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.basePath, bytes.NewReader(data))
+	if err != nil {
+		s.logger.Error("building WebSocket request failed", "error", err)
+		return
+	}
+	req.Header = upgrade.Header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = upgrade.RemoteAddr
+
+	w := &wsResponseWriter{header: make(http.Header), send: send}
+	dispatch.ServeHTTP(w, req)
+	w.finish()
+}
+
+// wsResponseWriter adapts a WebSocket connection to http.ResponseWriter.
+// Streamed SSE frames are sent as they are flushed, one message per event;
+// any other response is sent as a single message once complete.
+type wsResponseWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+	send   func([]byte) error
+}
+
+func (w *wsResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *wsResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *wsResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.buf.Write(p)
+}
+
+// Flush sends every complete SSE frame written so far
+func (w *wsResponseWriter) Flush() {
+	if !w.streaming() {
+		return
+	}
+	for {
+		frame, rest, ok := bytes.Cut(w.buf.Bytes(), []byte("\n\n"))
+		if !ok {
+			return
+		}
+		w.sendFrame(frame)
+		remaining := bytes.Clone(rest)
+		w.buf.Reset()
+		w.buf.Write(remaining)
+	}
+}
+
+// finish sends whatever the handler left unsent
+func (w *wsResponseWriter) finish() {
+	if w.streaming() {
+		w.Flush()
+		if w.buf.Len() > 0 {
+			w.sendFrame(w.buf.Bytes())
+		}
+		return
+	}
+
+	if w.status == http.StatusNoContent {
+		// Notifications get no reply
+		return
+	}
+	body := bytes.TrimSpace(w.buf.Bytes())
+	if w.status != http.StatusOK || !strings.HasPrefix(w.header.Get("Content-Type"), "application/json") {
+		// Plain HTTP errors have no JSON-RPC form; wrap them so the client gets a reply
+		w.sendError(string(body))
+		return
+	}
+	w.send(body)
+}
+
+func (w *wsResponseWriter) streaming() bool {
+	return strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+}
+
+// sendFrame sends the data of one SSE frame, skipping comments
+func (w *wsResponseWriter) sendFrame(frame []byte) {
+	var data [][]byte
+	for _, line := range bytes.Split(frame, []byte("\n")) {
+		if payload, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+			data = append(data, payload)
+		}
+	}
+	if len(data) > 0 {
+		w.send(bytes.Join(data, []byte("\n")))
+	}
+}
+
+func (w *wsResponseWriter) sendError(message string) {
+	data, _ := json.Marshal(models.JSONRPCResponse{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+		},
+		Error: &models.JSONRPCError{
+			Code:    int(models.ErrorCodeInternalError),
+			Message: message,
+		},
+	})
+	w.send(data)
+}
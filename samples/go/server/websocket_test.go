@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"a2a/models"
+)
+
+// dialWebSocket starts server with WithWebSocket and connects to its /ws endpoint
+func dialWebSocket(t *testing.T, server *A2AServer) *websocket.Conn {
+	t.Helper()
+
+	ts := httptest.NewServer(server.newHTTPServer().Handler)
+	t.Cleanup(ts.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+websocketPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// sendWebSocketRequest writes a JSON-RPC request on conn
+func sendWebSocketRequest(t *testing.T, conn *websocket.Conn, id, method string, params interface{}) {
+	t.Helper()
+
+	err := conn.WriteJSON(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: id},
+		},
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+}
+
+func TestWebSocketStream(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithWebSocket())
+	conn := dialWebSocket(t, server)
+
+	sendWebSocketRequest(t, conn, "1", models.MethodMessageStream, models.TaskSendParams{
+		ID:      "test-task",
+		Message: models.NewTextMessage(models.RoleUser, "Hello"),
+	})
+
+	var states []models.TaskState
+	for {
+		var response struct {
+			Result models.TaskStatusUpdateEvent `json:"result"`
+		}
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		states = append(states, response.Result.Status.State)
+		if response.Result.Final != nil && *response.Result.Final {
+			break
+		}
+	}
+
+	if len(states) != 3 || states[0] != models.TaskStateSubmitted || states[1] != models.TaskStateWorking || states[2] != models.TaskStateCompleted {
+		t.Errorf("Expected [submitted working completed], got %v", states)
+	}
+
+	// The same connection carries plain request/response calls
+	sendWebSocketRequest(t, conn, "2", models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task"}})
+
+	var response models.JSONRPCResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if response.ID != "2" {
+		t.Errorf("Expected ID 2, got %v", response.ID)
+	}
+	var task models.Task
+	decodeResult(t, response, &task)
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
+
+func TestWebSocketMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithWebSocket())
+	server.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen = append(seen, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			next.ServeHTTP(w, r)
+		})
+	})
+	conn := dialWebSocket(t, server)
+
+	sendWebSocketRequest(t, conn, "1", models.MethodTasksGet, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+	var response models.JSONRPCResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	// The upgrade and then each message pass through the middleware
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"GET " + websocketPath, "POST /"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected middleware to see %v, got %v", want, seen)
+	}
+}
+
+func TestWebSocketInvalidRequest(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithWebSocket())
+	conn := dialWebSocket(t, server)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	var response models.JSONRPCResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeParseError) {
+		t.Errorf("Expected parse error, got %+v", response.Error)
+	}
+}
+
+func TestWebSocketDisabledByDefault(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	ts := httptest.NewServer(server.newHTTPServer().Handler)
+	defer ts.Close()
+
+	_, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+websocketPath, nil)
+	if err == nil {
+		t.Error("Expected dial to fail without WithWebSocket")
+	}
+}